@@ -0,0 +1,79 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+
+	"go.uber.org/zap"
+)
+
+// backlogStatus описывает сообщения, которые ещё не сохранены в хранилище
+type backlogStatus struct {
+	Queued   int `json:"queued"`   // сообщения в канале msgChan
+	Capacity int `json:"capacity"` // ёмкость канала msgChan
+	Buffered int `json:"buffered"` // сообщения, прочитанные flushMessages и ждущие сохранения
+}
+
+// adminRoutes возвращает маршрутизатор диагностического сервера.
+// Маршруты не требуют авторизации, поэтому сервер слушает отдельный адрес, недоступный снаружи.
+func (a *app) adminRoutes() http.Handler {
+	mux := http.NewServeMux()
+
+	registerPprof(mux)
+	mux.HandleFunc("/debug/goroutines", goroutines)
+	mux.HandleFunc("/debug/backlog", a.backlog)
+	mux.HandleFunc("/debug/buildinfo", buildInfo)
+	// GET отдаёт текущий уровень журнала, PUT с телом {"level":"debug"} меняет его
+	mux.Handle("/debug/loglevel", logger.Level)
+
+	return logger.RequestID(logger.RequestLogger(mux.ServeHTTP))
+}
+
+// registerPprof подключает к mux профили runtime/pprof
+func registerPprof(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// goroutines отдаёт стеки всех горутин в текстовом виде, как при панике
+func goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := rpprof.Lookup("goroutine").WriteTo(w, 2); err != nil {
+		logger.FromContext(r.Context()).Debug("cannot write goroutine dump", zap.Error(err))
+	}
+}
+
+// backlog сообщает, сколько сообщений ждут сохранения в канале и в пачке flushMessages
+func (a *app) backlog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(backlogStatus{
+		Queued:   len(a.msgChan),
+		Capacity: cap(a.msgChan),
+		Buffered: int(a.pending.Load()),
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Debug("error encoding response", zap.Error(err))
+	}
+}
+
+// buildInfo отдаёт версию Go, модуль и параметры сборки навыка
+func buildInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		// бинарник собран без поддержки модулей, известна только версия Go
+		fmt.Fprintln(w, "go", runtime.Version())
+		return
+	}
+	fmt.Fprint(w, info.String())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	a := &app{msgChan: make(chan queuedMessage, 4)}
+	a.msgChan <- queuedMessage{}
+	a.pending.Store(3)
+	srv := httptest.NewServer(a.adminRoutes())
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		contains     string
+	}{
+		{name: "pprof_index", path: "/debug/pprof/", expectedCode: http.StatusOK, contains: "goroutine"},
+		{name: "goroutines", path: "/debug/goroutines", expectedCode: http.StatusOK, contains: "goroutine "},
+		{name: "buildinfo", path: "/debug/buildinfo", expectedCode: http.StatusOK, contains: "go"},
+		{name: "webhook_is_not_served", path: "/alice/webhook", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedCode, resp.StatusCode)
+			if tc.contains != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(body), tc.contains)
+			}
+		})
+	}
+
+	t.Run("loglevel", func(t *testing.T) {
+		t.Cleanup(func() { logger.Level.SetLevel(zapcore.InfoLevel) })
+
+		r, err := http.NewRequest(http.MethodPut, srv.URL+"/debug/loglevel", strings.NewReader(`{"level":"debug"}`))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(r)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, zapcore.DebugLevel, logger.Level.Level())
+	})
+
+	t.Run("backlog", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/debug/backlog")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var status backlogStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.Equal(t, backlogStatus{Queued: 1, Capacity: 4, Buffered: 3}, status)
+	})
+}
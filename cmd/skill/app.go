@@ -1,32 +1,71 @@
 package main
 
 import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/dialog"
+	"alice-skill/internal/intent"
 	"alice-skill/internal/logger"
+	"alice-skill/internal/metrics"
 	"alice-skill/internal/models"
+	"alice-skill/internal/ratelimit"
 	"alice-skill/internal/store"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // app инкапсулирует в себя все зависимости и логику приложения
 type app struct {
 	store   store.Store
-	msgChan chan store.Message // канал для отложенной отправки новых сообщений
+	dialogs *dialog.Manager    // ведёт диалоги и выбирает обработчик реплики
+	msgChan chan queuedMessage // канал для отложенной отправки новых сообщений
+	pending atomic.Int64       // число сообщений, прочитанных из канала, но ещё не сохранённых
+	timeout time.Duration      // время, за которое навык должен ответить Алисе
+	limiter *ratelimit.Limiter // ограничивает частоту команд пользователей
+	strict  bool               // отклонять запросы с лишними данными после JSON и неизвестными полями верхнего уровня
+
+	clientIPHeader string // заголовок с адресом клиента от доверенного прокси; пустая строка — адрес неизвестен
+
+	grammarFile string // файл грамматики; пустая строка — используется встроенная грамматика
+}
+
+// queuedMessage — сообщение в очереди на сохранение вместе с контекстом трассы запроса, который его создал
+type queuedMessage struct {
+	store.Message
+	origin trace.SpanContext
 }
 
+// tracer создаёт спаны этапов обработки запроса и фонового сохранения сообщений
+var tracer = otel.Tracer("alice-skill/cmd/skill")
+
+// Алиса прерывает запрос примерно через 3 секунды, поэтому отвечаем с запасом на сеть
+const defaultResponseTimeout = 2500 * time.Millisecond
+
+// ответ, который навык даёт, если не успел обработать реплику вовремя
+const busyText = "Сервис занят, попробуйте ещё раз"
+
+// ответ, который навык даёт, если не успел ответить, но уже начал выполнять команду: повтор мог бы её удвоить
+const uncertainText = "Не успела дождаться результата. Проверьте, выполнилась ли команда, прежде чем повторять её."
+
 // newApp принимает на вход внешние зависимости приложения и возвращает новый объект app
 func newApp(s store.Store) *app {
 	instance := &app{
 		store:   s,
-		msgChan: make(chan store.Message, 1024), // установим каналу буфер в 1024 сообщения
+		msgChan: make(chan queuedMessage, 1024), // установим каналу буфер в 1024 сообщения
+		timeout: defaultResponseTimeout,
+		limiter: ratelimit.New(ratelimit.NewMemoryBuckets(), defaultRateLimits),
 	}
+	instance.dialogs = dialog.NewManager(instance.defaultRouter(), dialog.NewSessionFrames(dialog.DefaultTTL))
+	metrics.TrackMessageQueue(func() int { return len(instance.msgChan) })
 
 	// запустим горутину с фоновым сохранением новых сообщений
 	go instance.flushMessages()
@@ -36,181 +75,210 @@ func newApp(s store.Store) *app {
 
 // обработчик HTTP-запроса
 func (a *app) webhook(w http.ResponseWriter, r *http.Request) {
-	var req models.Request
-
 	ctx := r.Context()
 
 	// разрешён только POST-метод
 	if r.Method != http.MethodPost {
-		logger.Log.Debug("got request with bad method", zap.String("method", r.Method))
+		logger.FromContext(ctx).Debug("got request with bad method", zap.String("method", r.Method))
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
+	// Алиса присылает JSON; запрос без Content-Type тоже принимаем
+	if !isJSONContentType(r) {
+		logger.FromContext(ctx).Debug("unsupported content type", zap.String("content_type", r.Header.Get("Content-Type")))
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
 	// десериализуем запрос в структуру модели
-	logger.Log.Debug("decoding request")
+	logger.FromContext(ctx).Debug("decoding request")
+
+	_, span := tracer.Start(ctx, "webhook.decode")
+	req, err := decodeRequest(r.Body, a.strict)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "cannot decode request")
+		span.End()
+		// слишком большое тело отличаем от испорченного, чтобы клиент понял, что исправлять
+		logger.FromContext(ctx).Debug("cannot decode request JSON body", zap.Error(err))
+		w.WriteHeader(bodyErrorStatus(err))
+		return
+	}
+	span.End()
 
-	dec := json.NewDecoder(r.Body)
-	if err := dec.Decode(&req); err != nil {
-		logger.Log.Debug("cannot decode request JSON body", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+	// дополним журнал доступа и логер запроса идентификаторами Алисы, пользователя записываем только отпечатком
+	ids := []zap.Field{
+		zap.String("session_id", req.Session.SessionID),
+		zap.Int("message_id", req.Session.MessageID),
+		zap.String("user", logger.HashUserID(req.Session.Identity())),
+	}
+	// по идентификатору трассы строки журнала находятся рядом со спанами запроса
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		ids = append(ids, zap.String("trace_id", sc.TraceID().String()))
+	}
+	logger.Annotate(ctx, ids...)
+	ctx = logger.With(ctx, ids...)
+	log := logger.FromContext(ctx)
+
+	// на проверки доступности от платформы отвечаем сразу, не обращаясь к хранилищу
+	if isHealthCheck(req) {
+		log.Debug("answering health check")
+		metrics.SetIntent(ctx, "ping")
+		replyHealthCheck(ctx, w)
 		return
 	}
 
-	// проверяем, что пришёл запрос понятного типа
-	if req.Request.Type != models.TypeSimpleUtterance {
-		logger.Log.Debug("unsupported request type", zap.String("type", req.Request.Type))
-		w.WriteHeader(http.StatusUnprocessableEntity)
+	// статус, отличный от 200, получают только запросы, нарушающие протокол; на остальные навык отвечает фразой
+	switch req.Request.Type {
+	case models.TypeSimpleUtterance:
+	case models.TypeButtonPressed:
+		// кнопок навык не показывает, но нажатие — корректный запрос, и диалог должен продолжиться
+		err := apperr.User(unsupportedText, fmt.Errorf("unsupported request type %q", req.Request.Type))
+		writeResponse(ctx, w, errorResponse(ctx, err))
+		return
+	default:
+		log.Debug("unknown request type", zap.String("type", req.Request.Type))
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// текст ответа навыка
-	var text string
+	// Алиса ждёт ответа ограниченное время, поэтому вся обработка, включая запросы к хранилищу, укладывается в бюджет
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
 
-	switch true {
-	// пользователь попросил отправить сообщение
-	case strings.HasPrefix(req.Request.Command, "Отправь"):
-		// гипотетическая функция parseSendCommand вычленит из запроса логин адресата и текст сообщения
-		username, message := parseSendCommand(req.Request.Command)
+	resp, err := a.respondWithin(ctx, &req, clientIP(r, a.clientIPHeader))
+	// клиент мог сам закрыть соединение, тогда отвечать уже некому
+	if r.Context().Err() != nil {
+		log.Debug("client closed connection", zap.Error(err))
+		return
+	}
+	if err != nil {
+		resp = errorResponse(ctx, err)
+	}
 
-		// найдём внутренний идентификатор адресата по его логину
-		recipientID, err := a.store.FindRecipient(ctx, username)
-		if err != nil {
-			logger.Log.Debug("cannot find recipient by username", zap.String("username", username), zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	writeResponse(ctx, w, resp)
+	log.Debug("sending HTTP 200 response")
+}
 
-		// отправим сообщение в очередь на сохранение
-		a.msgChan <- store.Message{
-			Sender:    req.Session.User.UserID,
-			Recepient: recipientID,
-			Time:      time.Now(),
-			Payload:   message,
-		}
+// respondWithin готовит ответ на реплику, а если не успевает до истечения ctx — возвращает временную ошибку,
+// в ответ на которую пользователь услышит, что сервис занят. Обработка может не реагировать на отмену контекста,
+// поэтому ошибка возвращается по истечении бюджета, не дожидаясь её.
+// Если к этому моменту обработчик уже начал изменять данные, пользователь услышит не просьбу повторить,
+// а просьбу проверить результат; начать изменения после истечения срока обработчик не сможет, см. beginEffects.
+func (a *app) respondWithin(ctx context.Context, req *models.Request, ip string) (models.Response, error) {
+	type result struct {
+		resp models.Response
+		err  error
+	}
 
-		// сохраняем новое сообщение в СУБД, после успешного сохранения оно станет доступно для прослушивания получателем
-		// err = a.store.SaveMessage(ctx, recipientID, store.Message{
-		// 	Sender:  req.Session.User.UserID,
-		// 	Time:    time.Now(),
-		// 	Payload: message,
-		// })
-		// if err != nil {
-		// 	logger.Log.Debug("cannot save message", zap.String("recipient", recipientID), zap.Error(err))
-		// 	w.WriteHeader(http.StatusInternalServerError)
-		// 	return
-		// }
-
-		// Оповестим отправителя об успешности операции
-		text = "Сообщение успешно отправлено"
-
-		// пользователь попросил прочитать сообщение
-	case strings.HasPrefix(req.Request.Command, "Прочитай"):
-		// гипотетическая функция parseReadCommand вычленит из запроса порядковый номер сообщения в списке доступных
-		messageIndex := parseReadCommand(req.Request.Command)
-
-		// получим список непрослушанных сообщений пользователя
-		messages, err := a.store.ListMessages(ctx, req.Session.User.UserID)
-		if err != nil {
-			logger.Log.Debug("cannot load messages for user", zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+	effects := new(atomic.Int32)
+	ctx = context.WithValue(ctx, effectsKey{}, effects)
 
-		text = "Для вас нет новых сообщений."
-		if len(messages) < messageIndex {
-			// пользователь попросил прочитать сообщение, которого нет
-			text = "Такого сообщения не существует."
-		} else {
-			// получим сообщение по идентификатору
-			messageID := messages[messageIndex].ID
-			message, err := a.store.GetMessage(ctx, messageID)
-			if err != nil {
-				logger.Log.Debug("cannot load message", zap.Int64("id", messageID), zap.Error(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				return
+	done := make(chan result, 1)
+	go func() {
+		// паника в отдельной горутине не доходит до recoverPanics и завершила бы весь процесс
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- result{err: recovered(ctx, rec)}
 			}
+		}()
 
-			// передадим текст сообщения в ответе
-			text = fmt.Sprintf("Сообщение от %s, отправлено %s: %s", message.Sender, message.Time, message.Payload)
-		}
-
-	// пользователь хочет зарегистрироваться
-	case strings.HasPrefix(req.Request.Command, "Зарегистрируй"):
-		// гипотетическая функция parseRegisterCommand вычленит из запроса желаемое имя нового пользователя
-		username := parseRegisterCommand(req.Request.Command)
+		resp, err := a.respond(ctx, req, ip)
+		done <- result{resp: resp, err: err}
+	}()
 
-		// регистрируем пользователя
-		err := a.store.RegisterUser(ctx, req.Session.User.UserID, username)
-
-		// наличие неспецифичной ошибки
-		if err != nil && !errors.Is(err, store.ErrConflict) {
-			logger.Log.Debug("cannot register user", zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+	var err error
+	select {
+	case res := <-done:
+		if !errors.Is(res.err, context.DeadlineExceeded) {
+			return res.resp, res.err
 		}
+		err = res.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 
-		// определяем правильное ответное сообщение пользователю
-		text = fmt.Sprintf("Вы успешно зарегистрированы под именем %s", &username)
-		if errors.Is(err, store.ErrConflict) {
-			// ошибка специфична для случая конфликта имён пользователей
-			text = "Извините, такое имя уже занято. Попробуйте другое."
-		}
+	// клиент мог сам закрыть соединение, тогда отвечать уже некому
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return models.Response{}, err
+	}
 
-		// если не поняли команду, просто скажем пользователю, сколько у него новых сообщений
-	default:
+	metrics.DegradedResponses.WithLabelValues(metrics.ReasonDeadline).Inc()
+	logger.Annotate(ctx, zap.String("degraded", metrics.ReasonDeadline))
 
-		// получаем список сообщений для текущего пользователя
-		messages, err := a.store.ListMessages(ctx, req.Session.User.UserID)
-		if err != nil {
-			logger.Log.Debug("cannot load messages for user", zap.Error(err))
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+	err = fmt.Errorf("response deadline %s exceeded: %w", a.timeout, err)
+	if !effects.CompareAndSwap(effectsAllowed, effectsExpired) {
+		logger.FromContext(ctx).Warn("response deadline exceeded after changing data", zap.Error(err))
+		return models.Response{}, apperr.User(uncertainText, err)
+	}
+	return models.Response{}, apperr.Temporary(err)
+}
 
-		// формируем текст с количеством сообщений
-		text := "Для вас нет новых сообщений."
-		if len(messages) > 0 {
-			text = fmt.Sprintf("Для вас %d новых сообщений.", len(messages))
-		}
+// состояния обработки реплики относительно изменения данных
+const (
+	effectsAllowed int32 = iota // данные не изменялись, срок ответа не истёк
+	effectsStarted              // обработчик начал изменять данные
+	effectsExpired              // срок ответа истёк, пользователю предложено повторить команду
+)
 
-		// первый запрос новой сессии
-		if req.Session.New {
-			// обрабатываем поле Timezone запроса
-			tz, err := time.LoadLocation(req.Timezone)
-			if err != nil {
-				logger.Log.Debug("cannot parse timezone")
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
+// effectsKey — ключ контекста, под которым respondWithin передаёт обработчику состояние изменения данных
+type effectsKey struct{}
 
-			// получаем текущее время в часовом поясе пользователя
-			now := time.Now().In(tz)
-			hour, minute, _ := now.Clock()
+// beginEffects вызывается обработчиком непосредственно перед изменением данных: отправкой сообщения, записью в хранилище.
+// Если срок ответа уже истёк, пользователь услышал просьбу повторить команду, и изменение удвоилось бы при повторе,
+// поэтому beginEffects возвращает ошибку, и обработчик должен прекратить работу.
+func beginEffects(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	effects, ok := ctx.Value(effectsKey{}).(*atomic.Int32)
+	if !ok || effects.CompareAndSwap(effectsAllowed, effectsStarted) || effects.Load() == effectsStarted {
+		return nil
+	}
+	return fmt.Errorf("too late to change data: %w", context.DeadlineExceeded)
+}
 
-			// формируем новый текст приветствия
-			text = fmt.Sprintf("Точное время %d часов, %d минут. %s", hour, minute, text)
-		}
+// respond загружает настройки пользователя и получает ответ на реплику от менеджера диалогов
+func (a *app) respond(ctx context.Context, req *models.Request, ip string) (models.Response, error) {
+	// загружаем персональные настройки пользователя, они влияют на все ответы навыка
+	settings, err := a.loadSettings(ctx, req.Session.Identity())
+	if err != nil {
+		return models.Response{}, fmt.Errorf("cannot load user settings: %w", err)
 	}
 
-	// заполняем модель ответа
+	// модель ответа, её текст заполнится ниже
 	resp := models.Response{
-		Response: models.ResponsePayload{
-			Text: text, // Алиса проговорит текст
-		},
 		Version: "1.0",
 	}
 
-	// установка правильного заголовка для типа данных
-	w.Header().Set("Content-Type", "application/json")
-
-	// сериализуем ответ сервера
-	enc := json.NewEncoder(w)
-	if err := enc.Encode(resp); err != nil {
-		logger.Log.Debug("error encoding response", zap.Error(err))
-		return
+	// выбираем интент по реплике и получаем текст ответа навыка
+	turn := &intent.Turn{
+		Request:  req,
+		Response: &resp,
+		Settings: settings,
+		Location: userLocation(settings, req.Meta.Timezone),
+		ClientIP: ip,
+	}
+	hctx, span := tracer.Start(ctx, "intent.handle")
+	text, err := a.dialogs.Handle(hctx, turn)
+	span.SetAttributes(attribute.String("alice.intent", turn.Intent))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "intent handler failed")
+	}
+	span.End()
+	logger.Annotate(ctx, zap.String("intent", turn.Intent))
+	metrics.SetIntent(ctx, turn.Intent)
+	if err != nil {
+		return models.Response{}, err
 	}
 
-	logger.Log.Debug("sending HTTP 200 response")
+	// заполняем модель ответа
+	resp.Response = models.ResponsePayload{
+		Text: text, // Алиса проговорит текст
+	}
+	return resp, nil
 }
 
 // flushMessages постоянно сохраняет несколько сообщений в хранилище с определённым интервалом
@@ -218,27 +286,58 @@ func (a *app) flushMessages() {
 	// будем сохранять сообщения, накопленные за последние 10 секунд
 	ticker := time.NewTicker(10 * time.Second)
 
-	var messages []store.Message
+	var messages []queuedMessage
 
 	for {
 		select {
 		case msg := <-a.msgChan:
 			// добавим сообщение в слайс для последующего сохранения
 			messages = append(messages, msg)
+			a.pending.Store(int64(len(messages)))
+			metrics.PendingMessages.Set(float64(len(messages)))
 		case <-ticker.C:
 			// подождём, пока придёт хотя бы одно сообщение
 			if len(messages) == 0 {
 				continue
 			}
 			// сохраним все пришедшие сообщения одновременно
-			err := a.store.SaveMessages(context.TODO(), messages...)
-			if err != nil {
+			metrics.FlushBatchSize.Observe(float64(len(messages)))
+			if err := a.saveBatch(context.Background(), messages); err != nil {
 				logger.Log.Debug("cannot save messages", zap.Error(err))
+				metrics.FlushFailures.Inc()
 				// не будем стирать сообщения, попробуем отправить их чуть позже
 				continue
 			}
 			// сотрём успешно отосланные сообщения
 			messages = nil
+			a.pending.Store(0)
+			metrics.PendingMessages.Set(0)
 		}
 	}
 }
+
+// saveBatch сохраняет пачку сообщений в отдельной трассе; её спан ссылается на трассы запросов, создавших сообщения
+func (a *app) saveBatch(ctx context.Context, batch []queuedMessage) error {
+	links := make([]trace.Link, 0, len(batch))
+	messages := make([]store.Message, 0, len(batch))
+	for _, m := range batch {
+		if m.origin.IsValid() {
+			links = append(links, trace.Link{SpanContext: m.origin})
+		}
+		messages = append(messages, m.Message)
+	}
+
+	ctx, span := tracer.Start(ctx, "flushMessages",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("messages.count", len(messages))),
+	)
+	defer span.End()
+
+	err := a.store.SaveMessages(ctx, messages...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "cannot save messages")
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// заголовок, в котором прокси перед навыком передаёт общий секрет, если в флагах не задан другой
+const defaultSecretHeader = "X-Skill-Secret"
+
+// authConfig описывает, какие запросы к webhook считаются подлинными
+type authConfig struct {
+	skillIDs     map[string]bool // разрешённые идентификаторы навыка; пустой список — проверка отключена
+	secretHeader string          // заголовок с общим секретом
+	secret       string          // общий секрет; пустая строка — проверка отключена
+	clientCert   bool            // требовать проверенный клиентский сертификат
+}
+
+// newAuthConfig собирает настройки проверки из флагов: список идентификаторов навыка задаётся через запятую
+func newAuthConfig(skillIDs, secretHeader, secret string, clientCert bool) authConfig {
+	cfg := authConfig{
+		skillIDs:     make(map[string]bool),
+		secretHeader: secretHeader,
+		secret:       secret,
+		clientCert:   clientCert,
+	}
+	if cfg.secretHeader == "" {
+		cfg.secretHeader = defaultSecretHeader
+	}
+	for _, id := range strings.Split(skillIDs, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			cfg.skillIDs[id] = true
+		}
+	}
+	return cfg
+}
+
+// authMiddleware пропускает к хендлеру только запросы, отправленные платформой для нашего навыка.
+// Идентификатор навыка читается из тела, поэтому middleware ставится после распаковки gzip.
+func authMiddleware(cfg authConfig) func(h http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// клиентский сертификат проверил TLS-сервер, здесь убеждаемся, что он был предъявлен
+			if cfg.clientCert && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+				logger.FromContext(r.Context()).Debug("request without verified client certificate")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if cfg.secret != "" {
+				got := r.Header.Get(cfg.secretHeader)
+				if subtle.ConstantTimeCompare([]byte(got), []byte(cfg.secret)) != 1 {
+					logger.FromContext(r.Context()).Debug("request with wrong shared secret", zap.String("header", cfg.secretHeader))
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+			}
+
+			if len(cfg.skillIDs) > 0 {
+				// запрос, нарушающий протокол, получает тот же статус, что и без проверки идентификатора навыка:
+				// не понятое тело — не повод считать запрос чужим
+				if r.Method != http.MethodPost {
+					logger.FromContext(r.Context()).Debug("got request with bad method", zap.String("method", r.Method))
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				if !isJSONContentType(r) {
+					logger.FromContext(r.Context()).Debug("unsupported content type", zap.String("content_type", r.Header.Get("Content-Type")))
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				skillID, err := readSkillID(r)
+				if err != nil {
+					logger.FromContext(r.Context()).Debug("cannot read skill_id from request", zap.Error(err))
+					w.WriteHeader(bodyErrorStatus(err))
+					return
+				}
+				if !cfg.skillIDs[skillID] {
+					logger.FromContext(r.Context()).Debug("request for unknown skill", zap.String("skill_id", skillID))
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
+			// передаём управление хендлеру
+			h(w, r)
+		}
+	}
+}
+
+// readSkillID достаёт session.skill_id из тела запроса и возвращает тело на место для следующего хендлера
+func readSkillID(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", errors.New("empty body")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("cannot read body: %w", err)
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Session struct {
+			SkillID string `json:"skill_id"`
+		} `json:"session"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", fmt.Errorf("cannot decode body: %w", err)
+	}
+	return req.Session.SkillID, nil
+}
+
+// newClientCertTLSConfig возвращает настройки TLS-сервера, проверяющие клиентский сертификат по указанному CA.
+// Сервер принимает и соединения без сертификата, чтобы проверки здоровья работали без него,
+// а обязательность сертификата для webhook обеспечивает authMiddleware.
+func newClientCertTLSConfig(caFile string) (*tls.Config, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", caFile)
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	const body = `{"session": {"skill_id": "skill1"}, "version": "1.0"}`
+
+	// хендлер проверяет, что тело запроса дошло до него целиком
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write(b)
+	}
+
+	testCases := []struct {
+		name         string
+		cfg          authConfig
+		body         string
+		header       http.Header
+		tls          *tls.ConnectionState
+		expectedCode int
+	}{
+		{
+			name:         "checks_disabled",
+			cfg:          newAuthConfig("", "", "", false),
+			body:         `{"session": {"skill_id": "other"}}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "allowed_skill",
+			cfg:          newAuthConfig("skill0, skill1", "", "", false),
+			body:         body,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown_skill",
+			cfg:          newAuthConfig("skill0", "", "", false),
+			body:         body,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "malformed_body",
+			cfg:          newAuthConfig("skill1", "", "", false),
+			body:         `{"session":`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported_content_type",
+			cfg:          newAuthConfig("skill1", "", "", false),
+			body:         body,
+			header:       http.Header{"Content-Type": {"text/plain"}},
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "valid_secret",
+			cfg:          newAuthConfig("skill1", "X-Secret", "s3cret", false),
+			body:         body,
+			header:       http.Header{"X-Secret": {"s3cret"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "wrong_secret",
+			cfg:          newAuthConfig("skill1", "X-Secret", "s3cret", false),
+			body:         body,
+			header:       http.Header{"X-Secret": {"guess"}},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing_client_cert",
+			cfg:          newAuthConfig("", "", "", true),
+			body:         body,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "verified_client_cert",
+			cfg:          newAuthConfig("", "", "", true),
+			body:         body,
+			tls:          &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := logger.RequestLogger(gzipMiddleware(authMiddleware(tc.cfg)(echo)))
+
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tc.body))
+			for k, v := range tc.header {
+				r.Header[k] = v
+			}
+			r.TLS = tc.tls
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(t, tc.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareGzip(t *testing.T) {
+	handler := logger.RequestLogger(gzipMiddleware(authMiddleware(newAuthConfig("skill1", "", "", false))(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)))
+
+	// идентификатор навыка читается уже из распакованного тела
+	buf := bytes.NewBuffer(nil)
+	zb := gzip.NewWriter(buf)
+	_, err := zb.Write([]byte(`{"session": {"skill_id": "skill1"}}`))
+	require.NoError(t, err)
+	require.NoError(t, zb.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", buf)
+	r.Header.Set("Content-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
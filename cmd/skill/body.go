@@ -0,0 +1,143 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"alice-skill/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ограничения размера тела запроса по умолчанию; запросы Алисы занимают единицы килобайт
+const (
+	defaultMaxBodySize         = 64 << 10  // тело в том виде, в каком оно пришло по сети
+	defaultMaxDecompressedSize = 256 << 10 // тело после распаковки gzip
+)
+
+// bodyLimits ограничивает размер тела запросов к webhook
+type bodyLimits struct {
+	maxBody         int64 // байт тела, пришедшего по сети
+	maxDecompressed int64 // байт тела после распаковки gzip
+}
+
+// orDefaults заменяет незаданные ограничения значениями по умолчанию
+func (l bodyLimits) orDefaults() bodyLimits {
+	if l.maxBody <= 0 {
+		l.maxBody = defaultMaxBodySize
+	}
+	if l.maxDecompressed <= 0 {
+		l.maxDecompressed = defaultMaxDecompressedSize
+	}
+	return l
+}
+
+// limitBody отклоняет запросы с телом больше maxBytes байт. Если размер известен заранее, запрос отклоняется сразу,
+// иначе чтение тела прекращается на границе и возвращает *http.MaxBytesError.
+// Поставленный до gzipMiddleware, ограничивает сжатое тело, после — распакованное.
+func limitBody(maxBytes int64) func(h http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				logger.FromContext(r.Context()).Debug("request body too large", zap.Int64("size", r.ContentLength), zap.Int64("limit", maxBytes))
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			h(w, r)
+		}
+	}
+}
+
+// bodyErrorStatus возвращает статус ответа на ошибку чтения или разбора тела запроса
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// isJSONContentType проверяет заголовок Content-Type запроса; отсутствующий заголовок допускается
+func isJSONContentType(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	return err == nil && mediaType == "application/json"
+}
+
+// decodeRequest читает запрос Алисы из body. В строгом режиме после JSON-объекта не должно быть других данных,
+// а на верхнем уровне — полей, которых нет в models.Request. Вложенные поля не проверяются:
+// платформа добавляет их в новых версиях протокола, и навык не должен из-за этого ломаться.
+func decodeRequest(body io.Reader, strict bool) (models.Request, error) {
+	var req models.Request
+
+	dec := json.NewDecoder(body)
+	if !strict {
+		err := dec.Decode(&req)
+		return req, err
+	}
+
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return req, err
+	}
+	// после объекта допускаются только пробельные символы
+	switch _, err := dec.Token(); {
+	case err == io.EOF:
+	case err != nil && !isSyntaxError(err):
+		// ошибка чтения, например превышение размера, важнее лишних данных
+		return req, err
+	default:
+		return req, errors.New("unexpected data after JSON object")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return req, err
+	}
+	var unknown []string
+	for name := range fields {
+		if !requestFields()[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return req, fmt.Errorf("unknown fields: %s", strings.Join(unknown, ", "))
+	}
+
+	err := json.Unmarshal(raw, &req)
+	return req, err
+}
+
+// isSyntaxError проверяет, что err — ошибка разбора JSON, а не чтения тела
+func isSyntaxError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	return errors.As(err, &syntaxErr)
+}
+
+// requestFields возвращает имена полей верхнего уровня запроса Алисы из JSON-тегов models.Request
+var requestFields = sync.OnceValue(func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(models.Request{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+})
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"alice-skill/internal/store/mock"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gzipped сжимает data в формате gzip
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDecodeRequest(t *testing.T) {
+	testCases := []struct {
+		name      string
+		body      string
+		lenientOK bool
+		strictOK  bool
+	}{
+		{name: "valid", body: `{"request": {"command": "привет"}, "version": "1.0"}`, lenientOK: true, strictOK: true},
+		{name: "trailing_whitespace", body: "{\"version\": \"1.0\"}\n\t ", lenientOK: true, strictOK: true},
+		// платформа добавляет вложенные поля в новых версиях протокола
+		{name: "unknown_nested_field", body: `{"meta": {"flags": ["new"]}, "version": "1.0"}`, lenientOK: true, strictOK: true},
+		{name: "trailing_garbage", body: `{"version": "1.0"}garbage`, lenientOK: true},
+		{name: "second_object", body: `{"version": "1.0"}{"version": "2.0"}`, lenientOK: true},
+		{name: "unknown_top_level_field", body: `{"version": "1.0", "debug": true}`, lenientOK: true},
+		{name: "truncated", body: `{"version": "1.0"`},
+		{name: "not_object", body: `["version"]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeRequest(strings.NewReader(tc.body), false)
+			assert.Equal(t, tc.lenientOK, err == nil, "lenient: %v", err)
+
+			_, err = decodeRequest(strings.NewReader(tc.body), true)
+			assert.Equal(t, tc.strictOK, err == nil, "strict: %v", err)
+		})
+	}
+}
+
+func TestWebhookBody(t *testing.T) {
+	ping := `{"request": {"type": "SimpleUtterance", "command": "ping", "original_utterance": "ping"}, "session": {"skill_id": "skill1"}, "version": "1.0"}`
+	// сжимается в несколько десятков байт, а распаковывается в мегабайт
+	bomb := `{"version": "1.0", "padding": "` + strings.Repeat("a", 1<<20) + `"}`
+	limits := bodyLimits{maxBody: 512, maxDecompressed: 1024}
+
+	testCases := []struct {
+		name         string
+		cfg          routesConfig
+		method       string // по умолчанию POST
+		strict       bool
+		body         []byte
+		header       http.Header
+		chunked      bool // размер тела заранее неизвестен
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			body:         []byte(ping),
+			header:       http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "valid_gzip",
+			body:         gzipped(t, ping),
+			header:       http.Header{"Content-Encoding": {"gzip"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "too_large",
+			body:         []byte(ping + strings.Repeat(" ", 512)),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "too_large_chunked",
+			body:         []byte(`{"version": "1.0", "padding": "` + strings.Repeat("a", 1024) + `"}`),
+			chunked:      true,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "gzip_bomb",
+			body:         gzipped(t, bomb),
+			header:       http.Header{"Content-Encoding": {"gzip"}},
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// идентификатор навыка читается из тела до обработчика, ограничение действует и там
+			name:         "gzip_bomb_with_auth",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			body:         gzipped(t, bomb),
+			header:       http.Header{"Content-Encoding": {"gzip"}},
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			// со списком разрешённых навыков тело разбирается до обработчика, но статусы остаются прежними
+			name:         "malformed_json_with_auth",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			body:         []byte(`{"session": `),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported_content_type_with_auth",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			body:         []byte(ping),
+			header:       http.Header{"Content-Type": {"text/plain"}},
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "method_get_with_auth",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			method:       http.MethodGet,
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "valid_with_auth",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			body:         []byte(ping),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "corrupt_gzip",
+			body:         []byte(ping),
+			header:       http.Header{"Content-Encoding": {"gzip"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unsupported_encoding",
+			body:         []byte(ping),
+			header:       http.Header{"Content-Encoding": {"br"}},
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "unsupported_content_type",
+			body:         []byte(ping),
+			header:       http.Header{"Content-Type": {"text/plain"}},
+			expectedCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:         "malformed_json",
+			body:         []byte(`{"request": `),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "trailing_garbage_lenient",
+			body:         []byte(ping + "garbage"),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "trailing_garbage_strict",
+			strict:       true,
+			body:         []byte(ping + "garbage"),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown_field_strict",
+			strict:       true,
+			body:         []byte(`{"version": "1.0", "debug": true}`),
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			a := newApp(mock.NewMockStore(ctrl))
+			a.strict = tc.strict
+
+			cfg := tc.cfg
+			cfg.body = limits
+			h := a.routes(cfg)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			r := httptest.NewRequest(method, "/alice/webhook", bytes.NewReader(tc.body))
+			for k, v := range tc.header {
+				r.Header[k] = v
+			}
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"alice-skill/internal/store/pg"
+	"alice-skill/internal/tracing"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// config содержит итоговые настройки навыка.
+// Значения собираются слоями, каждый следующий переопределяет предыдущий:
+// значения по умолчанию, файл конфигурации, переменные окружения, флаги командной строки.
+type config struct {
+	RunAddr         string        // адрес, на котором навык принимает запросы Алисы
+	LogLevel        string        // уровень журнала
+	DatabaseURI     string        // параметры соединения с СУБД
+	GrammarFile     string        // файл грамматики; если не задан, используется встроенная грамматика
+	ResponseTimeout time.Duration // время, за которое навык должен ответить Алисе
+	SkillIDs        string        // идентификаторы навыка через запятую, запросы для других навыков отклоняются
+	SecretHeader    string        // заголовок, в котором прокси перед навыком передаёт общий секрет
+	Secret          string        // общий секрет
+	TLSCert         string        // сертификат сервера для HTTPS
+	TLSKey          string        // ключ сервера для HTTPS
+	TLSClientCA     string        // CA, которым подписаны клиентские сертификаты
+	RateLimits      string        // ограничения частоты команд, дополняющие ограничения по умолчанию
+	ClientIPHeader  string        // заголовок, в котором доверенный прокси передаёт адрес клиента
+	AdminToken      string        // токен доступа к маршрутам /admin/, без него они отключены
+	AdminAddr       string        // адрес диагностического сервера; пустая строка — сервер не запускается
+	Pprof           bool          // отдавать профили по /debug/pprof/ на основном адресе
+	TraceExporter   string        // экспортёр спанов трассировки
+	MaxBodySize     int64         // наибольший размер тела запроса к webhook в байтах, как оно пришло по сети
+	MaxDecompressed int64         // наибольший размер тела запроса к webhook в байтах после распаковки gzip
+	StrictJSON      bool          // отклонять запросы с данными после JSON и неизвестными полями верхнего уровня
+}
+
+// defaultConfig возвращает настройки по умолчанию
+func defaultConfig() config {
+	return config{
+		RunAddr:         ":8080",
+		LogLevel:        "info",
+		ResponseTimeout: defaultResponseTimeout,
+		SecretHeader:    defaultSecretHeader,
+		TraceExporter:   tracing.ExporterNone,
+		MaxBodySize:     defaultMaxBodySize,
+		MaxDecompressed: defaultMaxDecompressedSize,
+	}
+}
+
+// setting связывает поле config с ключом файла конфигурации, переменной окружения и флагом
+type setting struct {
+	key    string     // ключ в файле конфигурации
+	env    string     // переменная окружения
+	flag   string     // флаг командной строки
+	usage  string     // описание флага
+	secret bool       // значение не выводится командой config print и может читаться из файла
+	value  flag.Value // поле config
+
+	redact func(string) string // скрывает в выводе config print только учётные данные; nil — значение скрывается целиком
+	fileOf string              // ключ секрета, который читается из файла по пути из этой настройки
+}
+
+// settings перечисляет все настройки; порядок определяет порядок вывода config print
+func (c *config) settings() []setting {
+	return []setting{
+		{key: "run_addr", env: "RUN_ADDR", flag: "a", usage: "address and port to run server", value: (*stringValue)(&c.RunAddr)},
+		{key: "log_level", env: "LOG_LEVEL", flag: "l", usage: "log level", value: (*stringValue)(&c.LogLevel)},
+		{key: "database_uri", env: "DATABASE_URI", flag: "d", usage: "database URI", secret: true, redact: pg.RedactDSN, value: (*stringValue)(&c.DatabaseURI)},
+		{key: "grammar_file", env: "GRAMMAR_FILE", flag: "g", usage: "intent grammar file", value: (*stringValue)(&c.GrammarFile)},
+		{key: "response_timeout", env: "RESPONSE_TIMEOUT", flag: "t", usage: "webhook response timeout", value: (*durationValue)(&c.ResponseTimeout)},
+		{key: "skill_ids", env: "SKILL_IDS", flag: "skill-ids", usage: "comma-separated list of allowed skill IDs", value: (*stringValue)(&c.SkillIDs)},
+		{key: "secret_header", env: "SECRET_HEADER", flag: "secret-header", usage: "header with the shared secret", value: (*stringValue)(&c.SecretHeader)},
+		{key: "secret", env: "SKILL_SECRET", flag: "secret", usage: "shared secret expected in the secret header", secret: true, value: (*stringValue)(&c.Secret)},
+		{key: "tls_cert", env: "TLS_CERT", flag: "tls-cert", usage: "server TLS certificate file", value: (*stringValue)(&c.TLSCert)},
+		{key: "tls_key", env: "TLS_KEY", flag: "tls-key", usage: "server TLS key file", value: (*stringValue)(&c.TLSKey)},
+		{key: "tls_client_ca", env: "TLS_CLIENT_CA", flag: "tls-client-ca", usage: "CA file to verify client certificates", value: (*stringValue)(&c.TLSClientCA)},
+		{key: "rate_limits", env: "RATE_LIMITS", flag: "rate-limits", usage: "rate limits like send=10/m:5,default=1/s:20,ip=100/s:200", value: (*stringValue)(&c.RateLimits)},
+		{key: "client_ip_header", env: "CLIENT_IP_HEADER", flag: "client-ip-header", usage: "header with the client address set by a trusted reverse proxy, e.g. X-Real-IP; per-IP rate limits apply only when it is set", value: (*stringValue)(&c.ClientIPHeader)},
+		{key: "admin_token", env: "ADMIN_TOKEN", flag: "admin-token", usage: "token for /admin/ routes, empty disables them", secret: true, value: (*stringValue)(&c.AdminToken)},
+		{key: "admin_addr", env: "ADMIN_ADDR", flag: "admin-addr", usage: "address of the diagnostics server with pprof, goroutines, backlog and build info", value: (*stringValue)(&c.AdminAddr)},
+		{key: "pprof", env: "PPROF", flag: "pprof", usage: "serve /debug/pprof/ on the main address", value: (*boolValue)(&c.Pprof)},
+		{key: "trace_exporter", env: "TRACE_EXPORTER", flag: "trace-exporter", usage: "trace exporter: none, stdout or otlp (configured by OTEL_EXPORTER_OTLP_* variables)", value: (*stringValue)(&c.TraceExporter)},
+		{key: "max_body_size", env: "MAX_BODY_SIZE", flag: "max-body-size", usage: "max webhook request body size in bytes as received", value: (*int64Value)(&c.MaxBodySize)},
+		{key: "max_decompressed_size", env: "MAX_DECOMPRESSED_SIZE", flag: "max-decompressed-size", usage: "max webhook request body size in bytes after gzip decompression", value: (*int64Value)(&c.MaxDecompressed)},
+		{key: "strict_json", env: "STRICT_JSON", flag: "strict-json", usage: "reject trailing data and unknown top-level fields in webhook requests", value: (*boolValue)(&c.StrictJSON)},
+	}
+}
+
+// sources дополняет settings настройками, читающими секреты из файлов, как это принято для секретов Docker и Kubernetes:
+// database_uri_file в файле конфигурации, DATABASE_URI_FILE в окружении и -database-uri-file в командной строке
+func (c *config) sources() []setting {
+	sources := c.settings()
+	for _, s := range c.settings() {
+		if !s.secret {
+			continue
+		}
+		sources = append(sources, setting{
+			key:    s.key + "_file",
+			env:    s.env + "_FILE",
+			flag:   strings.ReplaceAll(s.key, "_", "-") + "-file",
+			usage:  "file to read " + s.key + " from",
+			value:  fileValue{target: s.value},
+			fileOf: s.key,
+		})
+	}
+	return sources
+}
+
+// conflicts возвращает ошибки для секретов, которые в одном слое заданы и значением, и файлом.
+// name возвращает имя настройки в слое, пустая строка — настройка в слое не задана.
+func conflicts(sources []setting, name func(s setting) string) []error {
+	names := make(map[string]string, len(sources))
+	for _, s := range sources {
+		names[s.key] = name(s)
+	}
+
+	var errs []error
+	for _, s := range sources {
+		if s.fileOf != "" && names[s.key] != "" && names[s.fileOf] != "" {
+			errs = append(errs, fmt.Errorf("%s and %s are both set, use only one", names[s.fileOf], names[s.key]))
+		}
+	}
+	return errs
+}
+
+// переменная окружения и флаг с путём к файлу конфигурации
+const (
+	configFileEnv  = "CONFIG_FILE"
+	configFileFlag = "config"
+)
+
+// loadConfig собирает настройки из значений по умолчанию, файла конфигурации, окружения getenv и аргументов args.
+// Ошибки всех слоёв и проверок собираются вместе, чтобы их можно было исправить за один раз;
+// вместе с ошибкой возвращаются настройки, собранные из корректных значений.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	cfg := defaultConfig()
+	sources := cfg.sources()
+
+	// флаги разбираем первыми, потому что среди них путь к файлу, но применяем последними
+	fs := flag.NewFlagSet("alice-skill", flag.ContinueOnError)
+	configFile := fs.String(configFileFlag, getenv(configFileEnv), "YAML or TOML configuration file")
+	flags := make(map[string]string)
+	for _, s := range sources {
+		usage := s.usage
+		if def := s.value.String(); def != "" && def != "false" {
+			usage += " (default " + def + ")"
+		}
+		record := func(v string) error {
+			flags[s.flag] = v
+			return nil
+		}
+		if _, ok := s.value.(*boolValue); ok {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	var errs []error
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, conflicts(sources, func(s setting) string {
+		if getenv(s.env) == "" {
+			return ""
+		}
+		return "env " + s.env
+	})...)
+	for _, s := range sources {
+		v := getenv(s.env)
+		if v == "" {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %w", s.env, err))
+		}
+	}
+
+	errs = append(errs, conflicts(sources, func(s setting) string {
+		if _, ok := flags[s.flag]; !ok {
+			return ""
+		}
+		return "flag -" + s.flag
+	})...)
+	for _, s := range sources {
+		v, ok := flags[s.flag]
+		if !ok {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", s.flag, err))
+		}
+	}
+
+	errs = append(errs, cfg.validate()...)
+	return cfg, errors.Join(errs...)
+}
+
+// loadFile применяет значения из файла конфигурации; формат определяется по расширению
+func (c *config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	values := make(map[string]any)
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	sources := c.sources()
+	errs := conflicts(sources, func(s setting) string {
+		if _, ok := values[s.key]; !ok {
+			return ""
+		}
+		return "config file " + path + ": " + s.key
+	})
+	known := make(map[string]bool)
+	for _, s := range sources {
+		known[s.key] = true
+
+		v, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		var str string
+		switch v := v.(type) {
+		case nil:
+			// пустое значение, например «secret:» в YAML, сбрасывает настройку
+		case map[string]any, []any:
+			errs = append(errs, fmt.Errorf("config file %s: %s: expected a single value", path, s.key))
+			continue
+		default:
+			str = fmt.Sprint(v)
+		}
+		if err := s.value.Set(str); err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, s.key, err))
+		}
+	}
+
+	// опечатка в ключе иначе молча оставила бы значение по умолчанию
+	for key := range values {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate проверяет согласованность настроек и возвращает ошибки всех неверных полей
+func (c *config) validate() []error {
+	var errs []error
+
+	if c.RunAddr == "" {
+		errs = append(errs, errors.New("run_addr: must not be empty"))
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("log_level: %w", err))
+	}
+	if c.ResponseTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("response_timeout: must be positive, got %s", c.ResponseTimeout))
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("tls_cert, tls_key: must be set together"))
+	}
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		errs = append(errs, errors.New("tls_client_ca: client certificate verification requires tls_cert and tls_key"))
+	}
+	if _, err := rateLimits(c.RateLimits); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limits: %w", err))
+	}
+	if c.AdminAddr != "" && c.AdminAddr == c.RunAddr {
+		errs = append(errs, errors.New("admin_addr: must differ from run_addr"))
+	}
+	if c.MaxBodySize <= 0 {
+		errs = append(errs, fmt.Errorf("max_body_size: must be positive, got %d", c.MaxBodySize))
+	}
+	if c.MaxDecompressed <= 0 {
+		errs = append(errs, fmt.Errorf("max_decompressed_size: must be positive, got %d", c.MaxDecompressed))
+	}
+	switch c.TraceExporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("trace_exporter: unknown exporter %q, expected %s, %s or %s",
+			c.TraceExporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP))
+	}
+
+	return errs
+}
+
+// redacted заменяет непустые секреты в выводе config print
+const redacted = "REDACTED"
+
+// print выводит настройки в формате YAML, пригодном для файла конфигурации; секреты скрываются
+func (c *config) print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		v := s.value.String()
+		if s.secret && v != "" {
+			if s.redact != nil {
+				v = s.redact(v)
+			} else {
+				v = redacted
+			}
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: v},
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// значения полей config в виде flag.Value
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+// fileValue читает значение настройки target из файла; завершающий перевод строки отбрасывается
+type fileValue struct {
+	target flag.Value
+}
+
+func (v fileValue) Set(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read secret file: %w", err)
+	}
+	return v.target.Set(strings.TrimRight(string(data), "\r\n"))
+}
+
+func (v fileValue) String() string { return "" }
+
+type int64Value int64
+
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/logger"
+	"alice-skill/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// фразы, которыми навык отвечает на ошибки без собственной фразы
+const (
+	// внутренняя ошибка навыка
+	internalErrorText = "Извините, что-то пошло не так."
+	// реплика понятного протокола, но не того вида, который навык умеет обрабатывать
+	unsupportedText = "Я понимаю только голосовые и текстовые команды."
+)
+
+// errorResponse превращает ошибку обработки реплики в ответ Алисе.
+// Это единственное место, где ошибки становятся фразами: ответ всегда корректен, и диалог продолжается.
+func errorResponse(ctx context.Context, err error) models.Response {
+	e := apperr.From(err)
+	log := logger.FromContext(ctx)
+
+	text := e.Phrase
+	switch {
+	case e.UserFacing():
+		log.Debug("command failed", zap.Error(err))
+	case e.Retryable:
+		log.Warn("temporary failure, asking user to retry", zap.Error(err))
+		text = busyText
+	default:
+		log.Error("cannot handle request", zap.Error(err))
+		text = internalErrorText
+	}
+
+	return models.Response{
+		Response: models.ResponsePayload{Text: text},
+		Version:  "1.0",
+	}
+}
+
+// writeResponse отправляет Алисе ответ resp со статусом 200
+func writeResponse(ctx context.Context, w http.ResponseWriter, resp models.Response) {
+	// установка правильного заголовка для типа данных
+	w.Header().Set("Content-Type", "application/json")
+
+	// сериализуем ответ сервера
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.FromContext(ctx).Debug("error encoding response", zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponse(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "user", err: apperr.User("Нет такого пользователя.", store.ErrNotFound), want: "Нет такого пользователя."},
+		{name: "temporary", err: fmt.Errorf("cannot load user settings: %w", &net.OpError{Op: "dial", Err: errors.New("refused")}), want: busyText},
+		{name: "internal", err: errors.New("boom"), want: internalErrorText},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := errorResponse(context.Background(), tc.err)
+			assert.Equal(t, tc.want, resp.Response.Text)
+			assert.Equal(t, "1.0", resp.Version)
+		})
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	body := `{"request": {"type": "SimpleUtterance", "command": "отправь иван привет", "nlu": {"tokens": ["отправь", "иван", "привет"]}}, "session": {"user_id": "user1"}, "version": "1.0"}`
+
+	testCases := []struct {
+		name          string
+		findRecipient error
+		want          string
+	}{
+		{name: "unknown_recipient", findRecipient: store.ErrNotFound, want: "Пользователь иван не зарегистрирован"},
+		{name: "store_failure", findRecipient: errors.New("relation does not exist"), want: internalErrorText},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().GetSettings(gomock.Any(), "user1").Return(nil, store.ErrNotFound)
+			s.EXPECT().FindRecipient(gomock.Any(), "иван").Return("", tc.findRecipient)
+
+			w := httptest.NewRecorder()
+			newApp(s).webhook(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+			// ошибки хранилища и команды не превращаются в HTTP-статусы: Алиса получает фразу
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), tc.want)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"alice-skill/internal/grammar"
+	"alice-skill/internal/intent"
+	"alice-skill/internal/logger"
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// грамматика по умолчанию, с которой навык работает, если файл грамматики не указан
+//
+//go:embed grammar.yaml
+var defaultGrammar []byte
+
+// интервал, с которым проверяется, не изменился ли файл грамматики
+const grammarCheckInterval = 5 * time.Second
+
+// defaultRouter собирает роутер по встроенной грамматике; её корректность проверяют тесты
+func (a *app) defaultRouter() *intent.Router {
+	g, err := grammar.Parse(defaultGrammar)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default grammar: %v", err))
+	}
+	router, err := a.newRouter(g)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default grammar: %v", err))
+	}
+	return router
+}
+
+// loadGrammar компилирует файл грамматики и переключает на него диалоги
+func (a *app) loadGrammar(path string) error {
+	g, err := grammar.Load(path)
+	if err != nil {
+		return err
+	}
+	router, err := a.newRouter(g)
+	if err != nil {
+		return fmt.Errorf("grammar %s: %w", path, err)
+	}
+
+	a.dialogs.SetRouter(router)
+	return nil
+}
+
+// watchGrammar перезагружает файл грамматики, когда он изменяется, пока не отменён ctx.
+// Если новая версия содержит ошибки, навык продолжает работать с предыдущей.
+func (a *app) watchGrammar(ctx context.Context, path string) {
+	ticker := time.NewTicker(grammarCheckInterval)
+	defer ticker.Stop()
+
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Log.Error("cannot stat grammar file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+
+		if err := a.loadGrammar(path); err != nil {
+			logger.Log.Error("cannot reload grammar, keeping the previous one", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		logger.Log.Info("grammar reloaded", zap.String("path", path))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"alice-skill/internal/dialog"
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"alice-skill/internal/ratelimit"
+	"alice-skill/internal/store/mock"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGrammar(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mock.NewMockStore(ctrl)
+	s.EXPECT().FindRecipient(gomock.Any(), "ivan").Return("user2", nil).Times(2)
+
+	a := &app{
+		store:   s,
+		msgChan: make(chan queuedMessage, 2),
+		limiter: ratelimit.New(ratelimit.NewMemoryBuckets(), defaultRateLimits),
+	}
+	a.dialogs = dialog.NewManager(a.defaultRouter(), dialog.NewSessionFrames(dialog.DefaultTTL))
+
+	say := func(command string) string {
+		text, err := a.dialogs.Handle(context.Background(), &intent.Turn{
+			Request:  &models.Request{Request: models.SimpleUtterance{Command: command}},
+			Response: &models.Response{},
+			Location: time.UTC,
+		})
+		require.NoError(t, err)
+		return text
+	}
+
+	path := filepath.Join(t.TempDir(), "grammar.yaml")
+
+	// новый синоним подключается без пересборки навыка
+	require.NoError(t, os.WriteFile(path, []byte("intents:\n  send:\n    - черкни {recipient:name} {text:any}\n"), 0o600))
+	require.NoError(t, a.loadGrammar(path))
+	assert.Equal(t, "Сообщение успешно отправлено", say("черкни ivan привет"))
+	assert.Equal(t, "привет", (<-a.msgChan).Payload)
+
+	// грамматика с ошибкой отклоняется, навык продолжает работать с предыдущей
+	require.NoError(t, os.WriteFile(path, []byte("intents:\n  sing:\n    - спой {song:tune}\n"), 0o600))
+	err := a.loadGrammar(path)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `slot "song" has unknown type "tune"`)
+	assert.Equal(t, "Сообщение успешно отправлено", say("черкни ivan пока"))
+	assert.Equal(t, "пока", (<-a.msgChan).Payload)
+}
+
+func TestWatchGrammarStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		(&app{}).watchGrammar(ctx, filepath.Join(t.TempDir(), "grammar.yaml"))
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchGrammar did not stop after its context was cancelled")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"alice-skill/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// реплика, которой платформа Алисы проверяет, что навык жив
+const pingUtterance = "ping"
+
+// сколько ожидающих сохранения сообщений навык считает допустимым; больше — хранилище не успевает
+const maxFlushBacklog = 512
+
+// время, за которое хранилище должно ответить на проверку готовности
+const readinessTimeout = time.Second
+
+// isHealthCheck проверяет, что запрос — проверка доступности от платформы, то есть реплика ping.
+// Запрос без реплики проверкой не считается и отклоняется как нарушающий протокол.
+func isHealthCheck(req models.Request) bool {
+	return req.Request.Type == models.TypeSimpleUtterance &&
+		strings.EqualFold(strings.TrimSpace(req.Request.OriginalUtterance), pingUtterance)
+}
+
+// replyHealthCheck отвечает на проверку доступности, не обращаясь к хранилищу
+func replyHealthCheck(ctx context.Context, w http.ResponseWriter) {
+	writeResponse(ctx, w, models.Response{
+		Response: models.ResponsePayload{Text: "pong"},
+		Version:  "1.0",
+	})
+}
+
+// flushBacklog возвращает число сообщений, которые ещё не сохранены в хранилище
+func (a *app) flushBacklog() int {
+	return len(a.msgChan) + int(a.pending.Load())
+}
+
+// healthz сообщает, что процесс запущен и обслуживает запросы
+func (a *app) healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// readyz сообщает, готов ли навык принимать запросы: доступно ли хранилище и успевает ли оно сохранять сообщения
+func (a *app) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err := a.store.Ping(ctx); err != nil {
+		logger.FromContext(ctx).Warn("store is not ready", zap.Error(err))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "store is unavailable")
+		return
+	}
+
+	if backlog := a.flushBacklog(); backlog > maxFlushBacklog {
+		logger.FromContext(ctx).Warn("flush backlog is too large", zap.Int("backlog", backlog))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "flush backlog is too large: %d messages\n", backlog)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
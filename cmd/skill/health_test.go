@@ -0,0 +1,60 @@
+package main
+
+import (
+	"alice-skill/internal/store/mock"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadyz(t *testing.T) {
+	testCases := []struct {
+		name         string
+		pingErr      error
+		backlog      int
+		expectedCode int
+	}{
+		{
+			name:         "ready",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "store_unavailable",
+			pingErr:      errors.New("connection refused"),
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "flush_backlog",
+			backlog:      maxFlushBacklog + 1,
+			expectedCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().Ping(gomock.Any()).Return(tc.pingErr)
+
+			a := &app{store: s, msgChan: make(chan queuedMessage, 1)}
+			a.msgChan <- queuedMessage{}
+			a.pending.Store(int64(tc.backlog))
+
+			w := httptest.NewRecorder()
+			a.readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&app{}).healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok\n", w.Body.String())
+}
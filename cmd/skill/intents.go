@@ -0,0 +1,423 @@
+package main
+
+import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/grammar"
+	"alice-skill/internal/intent"
+	"alice-skill/internal/nlu"
+	"alice-skill/internal/reminder"
+	"alice-skill/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// имена интентов навыка
+const (
+	intentSend     = "send"
+	intentRead     = "read"
+	intentRegister = "register"
+	intentRemind   = "remind"
+	intentSnooze   = "snooze"
+	intentDone     = "done"
+	intentSettings = "settings"
+	intentStatus   = "status"
+)
+
+// newRouter связывает интенты навыка с фразами грамматики
+func (a *app) newRouter(g *grammar.Grammar) (*intent.Router, error) {
+	intents, err := g.Bind(a.intents())
+	if err != nil {
+		return nil, err
+	}
+	for i := range intents {
+		intents[i].Handler = a.limited(intents[i].Name, intents[i].Handler)
+	}
+
+	// если не поняли команду, просто скажем пользователю, сколько у него новых сообщений
+	fallback := intent.Intent{Name: intentStatus, Handler: a.limited(intentStatus, a.handleStatus)}
+	return intent.NewRouter(fallback, intents...), nil
+}
+
+// intents описывает интенты навыка, их слоты и обработчики; фразы, которыми их называют, задаёт грамматика
+func (a *app) intents() []intent.Intent {
+	return []intent.Intent{
+		{
+			Name: intentSend,
+			Slots: []intent.Slot{
+				{
+					Name:        "recipient",
+					Required:    true,
+					Prompt:      "Кому отправить?",
+					ShortPrompt: "Кому?",
+					Extract: func(t *intent.Turn) string {
+						recipient, _ := nlu.ParseSend(t.Request.Request)
+						return recipient
+					},
+					Answer: answerName,
+				},
+				{
+					Name:        "text",
+					Required:    true,
+					Prompt:      "Что передать?",
+					ShortPrompt: "Что?",
+					Extract: func(t *intent.Turn) string {
+						_, text := nlu.ParseSend(t.Request.Request)
+						return text
+					},
+				},
+			},
+			Handler: a.handleSend,
+		},
+		{
+			Name:    intentRead,
+			Handler: a.handleRead,
+		},
+		{
+			Name: intentRegister,
+			Slots: []intent.Slot{
+				{
+					Name:        "username",
+					Required:    true,
+					Prompt:      "Под каким именем вас зарегистрировать?",
+					ShortPrompt: "Под каким именем?",
+					Extract: func(t *intent.Turn) string {
+						return nlu.ParseRegister(t.Request.Request)
+					},
+					Answer: answerName,
+				},
+			},
+			Handler: a.handleRegister,
+		},
+		{
+			Name: intentRemind,
+			Slots: []intent.Slot{
+				{
+					Name:        "time",
+					Required:    true,
+					Prompt:      "Когда напомнить?",
+					ShortPrompt: "Когда?",
+					Extract:     extractReminderTime,
+					Answer:      extractReminderTime,
+				},
+				{
+					Name:        "text",
+					Required:    true,
+					Prompt:      "О чём напомнить?",
+					ShortPrompt: "О чём?",
+					Extract: func(t *intent.Turn) string {
+						return reminder.ParseText(t.Request.Request)
+					},
+				},
+				{
+					Name: "recurrence",
+					Extract: func(t *intent.Turn) string {
+						return string(reminder.ParseRecurrence(t.Request.Request.Command))
+					},
+				},
+			},
+			Handler: a.handleRemind,
+		},
+		{
+			Name:    intentSnooze,
+			Handler: a.handleSnooze,
+		},
+		{
+			Name:    intentDone,
+			Handler: a.handleDone,
+		},
+		{
+			Name:    intentSettings,
+			Handler: a.handleSettings,
+		},
+	}
+}
+
+// answerName извлекает имя из ответа на вопрос «Кому?» или «Как вас зовут?»
+func answerName(t *intent.Turn) string {
+	return nlu.ParseName(t.Request.Request)
+}
+
+// extractReminderTime извлекает время напоминания в часовом поясе пользователя и передаёт его в слот в формате RFC 3339
+func extractReminderTime(t *intent.Turn) string {
+	due, ok := reminder.ParseTime(t.Request.Request, time.Now().In(t.Location))
+	if !ok {
+		return ""
+	}
+	return due.Format(time.RFC3339)
+}
+
+// handleSend ставит сообщение другому пользователю в очередь на сохранение
+func (a *app) handleSend(ctx context.Context, t *intent.Turn) (string, error) {
+	username, message := t.Slots["recipient"], t.Slots["text"]
+
+	// найдём внутренний идентификатор адресата по его логину
+	recipientID, err := a.store.FindRecipient(ctx, username)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", apperr.User(
+			fmt.Sprintf("Пользователь %s не зарегистрирован. Проверьте имя и попробуйте ещё раз.", username),
+			fmt.Errorf("cannot find recipient %q: %w", username, err),
+		)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot find recipient %q: %w", username, err)
+	}
+
+	// отправим сообщение в очередь на сохранение, после сохранения оно станет доступно для прослушивания получателем
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	msg := queuedMessage{
+		Message: store.Message{
+			Sender:    t.Request.Session.Identity(),
+			Recepient: recipientID,
+			Time:      time.Now(),
+			Payload:   message,
+		},
+		origin: trace.SpanContextFromContext(ctx),
+	}
+	// переполненная очередь не должна держать обработчик дольше срока ответа
+	select {
+	case a.msgChan <- msg:
+	case <-ctx.Done():
+		return "", apperr.Temporary(fmt.Errorf("cannot queue message: %w", ctx.Err()))
+	}
+
+	// Оповестим отправителя об успешности операции
+	return pick(t.Settings, "Сообщение успешно отправлено", "Отправлено"), nil
+}
+
+// handleRead зачитывает сообщение по порядковому номеру или следующее за прочитанным
+func (a *app) handleRead(ctx context.Context, t *intent.Turn) (string, error) {
+	// состояние диалога хранит номер последнего прочитанного сообщения
+	states := a.stateFor(t.Request)
+	st, err := states.Load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("cannot load dialog state: %w", err)
+	}
+
+	// вычленим из запроса порядковый номер сообщения в списке доступных
+	messageIndex := nlu.ParseRead(t.Request.Request)
+	if strings.Contains(strings.ToLower(t.Request.Request.Command), "следующее") {
+		messageIndex = st.LastReadIndex + 1
+	}
+
+	// получим список непрослушанных сообщений пользователя
+	messages, err := a.store.ListMessages(ctx, t.Request.Session.Identity())
+	if err != nil {
+		return "", fmt.Errorf("cannot load messages for user: %w", err)
+	}
+
+	// упорядочим сообщения так, как предпочитает пользователь
+	sortMessages(t.Settings, messages)
+
+	switch {
+	case len(messages) == 0:
+		return pick(t.Settings, "Для вас нет новых сообщений.", "Сообщений нет."), nil
+	case messageIndex < 1 || messageIndex > len(messages):
+		// пользователь попросил прочитать сообщение, которого нет
+		return pick(t.Settings, "Такого сообщения не существует.", "Нет такого сообщения."), nil
+	}
+
+	// получим сообщение по идентификатору, пользователь нумерует сообщения с единицы
+	messageID := messages[messageIndex-1].ID
+	message, err := a.store.GetMessage(ctx, messageID)
+	if errors.Is(err, store.ErrNotFound) {
+		// сообщение могли удалить между получением списка и чтением
+		return "", apperr.User(
+			pick(t.Settings, "Такого сообщения не существует.", "Нет такого сообщения."),
+			fmt.Errorf("cannot load message %d: %w", messageID, err),
+		)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot load message %d: %w", messageID, err)
+	}
+
+	// запомним прочитанное сообщение, чтобы можно было попросить следующее
+	st.LastReadIndex = messageIndex
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	if err := states.Save(ctx, t.Response, st); err != nil {
+		return "", fmt.Errorf("cannot save dialog state: %w", err)
+	}
+
+	// передадим текст сообщения в ответе
+	return pick(t.Settings,
+		fmt.Sprintf("Сообщение от %s, отправлено %s: %s", message.Sender, message.Time.In(t.Location).Format("02.01 15:04"), message.Payload),
+		fmt.Sprintf("%s: %s", message.Sender, message.Payload),
+	), nil
+}
+
+// handleRegister регистрирует пользователя под выбранным именем
+func (a *app) handleRegister(ctx context.Context, t *intent.Turn) (string, error) {
+	username := t.Slots["username"]
+
+	// регистрируем пользователя
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	err := a.store.RegisterUser(ctx, t.Request.Session.Identity(), username)
+	if errors.Is(err, store.ErrConflict) {
+		// ошибка специфична для случая конфликта имён пользователей
+		return pick(t.Settings, "Извините, такое имя уже занято. Попробуйте другое.", "Имя занято, назовите другое."), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot register user: %w", err)
+	}
+
+	return pick(t.Settings,
+		fmt.Sprintf("Вы успешно зарегистрированы под именем %s", username),
+		fmt.Sprintf("Готово, вы %s", username),
+	), nil
+}
+
+// handleRemind создаёт напоминание пользователю самому себе
+func (a *app) handleRemind(ctx context.Context, t *intent.Turn) (string, error) {
+	// время напоминания называется в часовом поясе пользователя
+	due, err := time.Parse(time.RFC3339, t.Slots["time"])
+	if err != nil {
+		return "", fmt.Errorf("cannot parse reminder time slot: %w", err)
+	}
+	rec := store.Recurrence(t.Slots["recurrence"])
+	due = reminder.Schedule(due.In(t.Location), time.Now().In(t.Location), rec)
+
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	err = a.store.SaveReminder(ctx, store.Reminder{
+		UserID:      t.Request.Session.Identity(),
+		DueAt:       due,
+		ScheduledAt: due,
+		Recurrence:  rec,
+		Payload:     t.Slots["text"],
+	})
+	if err != nil {
+		return "", fmt.Errorf("cannot save reminder: %w", err)
+	}
+
+	hour, minute, _ := due.Clock()
+	return pick(t.Settings,
+		fmt.Sprintf("Хорошо, напомню в %d:%02d: %s", hour, minute, t.Slots["text"]),
+		fmt.Sprintf("Напомню в %d:%02d", hour, minute),
+	), nil
+}
+
+// handleSnooze откладывает самое раннее из сработавших напоминаний
+func (a *app) handleSnooze(ctx context.Context, t *intent.Turn) (string, error) {
+	due, err := a.store.ListReminders(ctx, t.Request.Session.Identity(), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("cannot load reminders for user: %w", err)
+	}
+	if len(due) == 0 {
+		return pick(t.Settings, "У вас нет активных напоминаний.", "Напоминаний нет."), nil
+	}
+
+	snooze := reminder.ParseSnooze(t.Request.Request.Command)
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	if err := a.store.RescheduleReminder(ctx, due[0].ID, time.Now().Add(snooze)); err != nil {
+		return "", fmt.Errorf("cannot snooze reminder %d: %w", due[0].ID, err)
+	}
+
+	return pick(t.Settings,
+		fmt.Sprintf("Напомню через %d минут: %s", int(snooze.Minutes()), due[0].Payload),
+		fmt.Sprintf("Отложено на %d минут", int(snooze.Minutes())),
+	), nil
+}
+
+// handleDone отмечает самое раннее из сработавших напоминаний выполненным
+func (a *app) handleDone(ctx context.Context, t *intent.Turn) (string, error) {
+	due, err := a.store.ListReminders(ctx, t.Request.Session.Identity(), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("cannot load reminders for user: %w", err)
+	}
+	if len(due) == 0 {
+		return pick(t.Settings, "У вас нет активных напоминаний.", "Напоминаний нет."), nil
+	}
+
+	// повторяющееся напоминание переносим на следующее срабатывание по расписанию, однократное закрываем;
+	// от времени срабатывания считать нельзя: отложенное напоминание сдвинулось бы навсегда
+	r := due[0]
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+	if next, ok := reminder.Next(r.Recurrence, r.ScheduledAt, time.Now().In(t.Location)); ok {
+		err = a.store.AdvanceReminder(ctx, r.ID, next)
+	} else {
+		err = a.store.CompleteReminder(ctx, r.ID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot complete reminder %d: %w", r.ID, err)
+	}
+
+	return pick(t.Settings, fmt.Sprintf("Отлично, напоминание «%s» выполнено.", r.Payload), "Отмечено."), nil
+}
+
+// handleSettings изменяет персональные настройки пользователя
+func (a *app) handleSettings(ctx context.Context, t *intent.Turn) (string, error) {
+	reply, changed := applySettingsCommand(&t.Settings, t.Request.Request.Command)
+	if !changed {
+		return reply, nil
+	}
+	if err := beginEffects(ctx); err != nil {
+		return "", err
+	}
+
+	if err := a.store.SaveSettings(ctx, t.Settings); err != nil {
+		return "", fmt.Errorf("cannot save user settings: %w", err)
+	}
+	return reply, nil
+}
+
+// handleStatus сообщает количество новых сообщений, а в начале сессии — ещё время и сработавшие напоминания
+func (a *app) handleStatus(ctx context.Context, t *intent.Turn) (string, error) {
+	// получаем список сообщений для текущего пользователя
+	messages, err := a.store.ListMessages(ctx, t.Request.Session.Identity())
+	if err != nil {
+		return "", fmt.Errorf("cannot load messages for user: %w", err)
+	}
+
+	// формируем текст с количеством сообщений
+	text := pick(t.Settings, "Для вас нет новых сообщений.", "Сообщений нет.")
+	if len(messages) > 0 {
+		text = pick(t.Settings,
+			fmt.Sprintf("Для вас %d новых сообщений.", len(messages)),
+			fmt.Sprintf("Новых сообщений: %d.", len(messages)),
+		)
+	}
+
+	// остальное говорим только в первом запросе новой сессии
+	if !t.Request.Session.New {
+		return text, nil
+	}
+
+	// получаем текущее время в часовом поясе пользователя
+	now := time.Now().In(t.Location)
+
+	// формируем новый текст приветствия, если пользователь не отключил объявление времени
+	if t.Settings.AnnounceTime {
+		hour, minute, _ := now.Clock()
+		text = fmt.Sprintf("Точное время %d часов, %d минут. %s", hour, minute, text)
+	}
+
+	// напомним о сработавших напоминаниях
+	due, err := a.store.ListReminders(ctx, t.Request.Session.Identity(), now)
+	if err != nil {
+		return "", fmt.Errorf("cannot load reminders for user: %w", err)
+	}
+	if len(due) > 0 {
+		payloads := make([]string, 0, len(due))
+		for _, r := range due {
+			payloads = append(payloads, r.Payload)
+		}
+		text = fmt.Sprintf("%s Напоминания: %s.", text, strings.Join(payloads, "; "))
+	}
+
+	return text, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookReminders(t *testing.T) {
+	// сработавшее напоминание, которое пользователь откладывает или отмечает выполненным
+	due := func(rec store.Recurrence) []store.Reminder {
+		scheduled := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+		return []store.Reminder{{ID: 7, UserID: "user1", DueAt: scheduled, ScheduledAt: scheduled, Recurrence: rec, Payload: "позвонить маме"}}
+	}
+
+	testCases := []struct {
+		name         string
+		command      string
+		expect       func(s *mock.MockStore)
+		expectedText string
+	}{
+		{
+			name:    "remind",
+			command: "напомни в 18:00 позвонить маме",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().SaveReminder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, r store.Reminder) error {
+					assert.Equal(t, "user1", r.UserID)
+					assert.Equal(t, "позвонить маме", r.Payload)
+					assert.Equal(t, store.RecurrenceNone, r.Recurrence)
+					assert.True(t, r.DueAt.After(time.Now()))
+					assert.Equal(t, r.DueAt, r.ScheduledAt)
+					hour, minute, _ := r.DueAt.Clock()
+					assert.Equal(t, []int{18, 0}, []int{hour, minute})
+					return nil
+				})
+			},
+			expectedText: "Хорошо, напомню в 18:00: позвонить маме",
+		},
+		{
+			name:    "snooze",
+			command: "отложи на 10 минут",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().ListReminders(gomock.Any(), "user1", gomock.Any()).Return(due(store.RecurrenceNone), nil)
+				s.EXPECT().RescheduleReminder(gomock.Any(), int64(7), gomock.Any()).DoAndReturn(func(_ context.Context, _ int64, dueAt time.Time) error {
+					assert.WithinDuration(t, time.Now().Add(10*time.Minute), dueAt, time.Minute)
+					return nil
+				})
+			},
+			expectedText: "Напомню через 10 минут: позвонить маме",
+		},
+		{
+			// однократное напоминание закрывается
+			name:    "done_once",
+			command: "готово",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().ListReminders(gomock.Any(), "user1", gomock.Any()).Return(due(store.RecurrenceNone), nil)
+				s.EXPECT().CompleteReminder(gomock.Any(), int64(7)).Return(nil)
+			},
+			expectedText: "Отлично, напоминание «позвонить маме» выполнено.",
+		},
+		{
+			// повторяющееся напоминание переносится на следующее срабатывание по расписанию
+			name:    "done_recurring",
+			command: "готово",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().ListReminders(gomock.Any(), "user1", gomock.Any()).Return(due(store.RecurrenceDaily), nil)
+				s.EXPECT().AdvanceReminder(gomock.Any(), int64(7), gomock.Any()).DoAndReturn(func(_ context.Context, _ int64, next time.Time) error {
+					assert.True(t, next.After(time.Now()))
+					assert.WithinDuration(t, time.Now(), next, 24*time.Hour)
+					hour, minute, _ := next.Clock()
+					assert.Equal(t, []int{9, 0}, []int{hour, minute})
+					return nil
+				})
+			},
+			expectedText: "Отлично, напоминание «позвонить маме» выполнено.",
+		},
+		{
+			name:    "snooze_without_reminders",
+			command: "отложи",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().ListReminders(gomock.Any(), "user1", gomock.Any()).Return(nil, nil)
+			},
+			expectedText: "У вас нет активных напоминаний.",
+		},
+		{
+			name:    "done_without_reminders",
+			command: "готово",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().ListReminders(gomock.Any(), "user1", gomock.Any()).Return(nil, nil)
+			},
+			expectedText: "У вас нет активных напоминаний.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// неожиданный вызов хранилища, например изменение напоминания, которого нет, завалит тест
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().GetSettings(gomock.Any(), gomock.Any()).Return(nil, store.ErrNotFound).AnyTimes()
+			tc.expect(s)
+
+			body := fmt.Sprintf(`{"request": {"type": "SimpleUtterance", "command": %q}, "session": {"user_id": "user1"}, "version": "1.0"}`, tc.command)
+			resp := postWebhook(t, newApp(s), body)
+			assert.Equal(t, tc.expectedText, resp.Response.Text)
+		})
+	}
+}
+
+func TestWebhookReadNext(t *testing.T) {
+	sent := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.UTC)
+	messages := []store.Message{
+		{ID: 11, Sender: "alice", Time: sent, Payload: "первое"},
+		{ID: 12, Sender: "bob", Time: sent.Add(time.Minute), Payload: "второе"},
+		{ID: 13, Sender: "carol", Time: sent.Add(2 * time.Minute), Payload: "третье"},
+	}
+
+	testCases := []struct {
+		name  string
+		state string // состояние, которое Алиса передала в запросе
+		// expect задаёт ожидания к хранилищу состояния, check — к состоянию, которое навык вернул Алисе
+		expect func(s *mock.MockStore)
+		check  func(t *testing.T, resp models.Response)
+	}{
+		{
+			name:   "alice_user_state",
+			state:  `{"user": {"dialog": {"last_read_index": 1}}}`,
+			expect: func(s *mock.MockStore) {},
+			check: func(t *testing.T, resp models.Response) {
+				assert.Equal(t, map[string]any{stateKey: map[string]any{"last_read_index": float64(2)}}, resp.UserStateUpdate)
+				assert.Nil(t, resp.ApplicationState)
+			},
+		},
+		{
+			name:   "alice_application_state",
+			state:  `{"application": {"dialog": {"last_read_index": 1}, "other": "value"}}`,
+			expect: func(s *mock.MockStore) {},
+			check: func(t *testing.T, resp models.Response) {
+				assert.Equal(t, map[string]any{stateKey: map[string]any{"last_read_index": float64(2)}, "other": "value"}, resp.ApplicationState)
+				assert.Nil(t, resp.UserStateUpdate)
+			},
+		},
+		{
+			// Алиса не передала состояния, номер хранится у навыка
+			name:  "store_fallback",
+			state: `null`,
+			expect: func(s *mock.MockStore) {
+				gomock.InOrder(
+					s.EXPECT().GetUserState(gomock.Any(), "user1").Return([]byte(`{"last_read_index":1}`), nil),
+					s.EXPECT().SaveUserState(gomock.Any(), "user1", []byte(`{"last_read_index":2}`)).Return(nil),
+				)
+			},
+			check: func(t *testing.T, resp models.Response) {
+				assert.Nil(t, resp.UserStateUpdate)
+				assert.Nil(t, resp.ApplicationState)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().GetSettings(gomock.Any(), gomock.Any()).Return(nil, store.ErrNotFound).AnyTimes()
+			s.EXPECT().ListMessages(gomock.Any(), "user1").Return(append([]store.Message(nil), messages...), nil)
+			// после первого прочитанного сообщения «следующее» — второе
+			s.EXPECT().GetMessage(gomock.Any(), int64(12)).Return(&messages[1], nil)
+			tc.expect(s)
+
+			body := fmt.Sprintf(`{"request": {"type": "SimpleUtterance", "command": "прочитай следующее"}, "session": {"user_id": "user1"}, "state": %s, "version": "1.0"}`, tc.state)
+			resp := postWebhook(t, newApp(s), body)
+			assert.Equal(t, "Сообщение от bob, отправлено 04.03 09:01: второе", resp.Response.Text)
+			tc.check(t, resp)
+		})
+	}
+}
+
+func TestWebhookShortReplies(t *testing.T) {
+	testCases := []struct {
+		name         string
+		command      string
+		expect       func(s *mock.MockStore)
+		expectedText string
+	}{
+		{
+			name:         "slot_prompt",
+			command:      "отправь",
+			expect:       func(s *mock.MockStore) {},
+			expectedText: "Кому?",
+		},
+		{
+			name:    "name_taken",
+			command: "зарегистрируй меня как ivan",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().RegisterUser(gomock.Any(), "user1", "ivan").Return(store.ErrConflict)
+			},
+			expectedText: "Имя занято, назовите другое.",
+		},
+		{
+			name:    "settings_changed",
+			command: "называй время при входе",
+			expect: func(s *mock.MockStore) {
+				s.EXPECT().SaveSettings(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedText: "Буду называть время.",
+		},
+		{
+			name:         "settings_unknown",
+			command:      "мой часовой пояс Атлантида",
+			expect:       func(s *mock.MockStore) {},
+			expectedText: "Не знаю такого города.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := store.DefaultSettings("user1")
+			settings.Verbosity = store.VerbosityShort
+
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().GetSettings(gomock.Any(), "user1").Return(&settings, nil)
+			tc.expect(s)
+
+			body := fmt.Sprintf(`{"request": {"type": "SimpleUtterance", "command": %q}, "session": {"session_id": "session1", "user_id": "user1"}, "version": "1.0"}`, tc.command)
+			resp := postWebhook(t, newApp(s), body)
+			assert.Equal(t, tc.expectedText, resp.Response.Text)
+		})
+	}
+}
+
+// postWebhook отправляет запрос Алисы навыку через тестовый сервер и возвращает разобранный ответ
+func postWebhook(t *testing.T, a *app, body string) models.Response {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(a.webhook))
+	defer srv.Close()
+
+	var result models.Response
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		SetResult(&result).
+		Post(srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	return result
+}
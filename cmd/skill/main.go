@@ -2,46 +2,184 @@ package main
 
 import (
 	"alice-skill/internal/logger"
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/ratelimit"
+	"alice-skill/internal/store/instrumented"
 	"alice-skill/internal/store/pg"
-	"database/sql"
+	"alice-skill/internal/tracing"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
 
 func main() {
-	parseFlags()
+	// подкоманда config print выводит итоговые настройки со скрытыми секретами и завершает работу
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		cfg, err := loadConfig(os.Args[3:], os.Getenv)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if perr := cfg.print(os.Stdout); perr != nil {
+			err = errors.Join(err, perr)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	if err := run(); err != nil {
+	if err := run(cfg); err != nil {
 		panic(err)
 	}
 }
 
 // для инициализации зависимостей сервера перед запуском
-func run() error {
-	if err := logger.Initialize(flagLogLevel); err != nil {
+func run(cfg config) error {
+	if err := logger.Initialize(cfg.LogLevel); err != nil {
+		return err
+	}
+	watchLogLevelSignals()
+
+	// фоновые задачи сервера завершаются вместе с ним
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	// трассировка настраивается до создания зависимостей, чтобы их спаны сразу попадали в экспортёр
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// отправим накопленные спаны, но не будем ждать недоступный коллектор бесконечно
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Log.Warn("cannot flush traces", zap.Error(err))
+		}
+	}()
+
+	// создаём соединение с СУБД PostgreSQL по строке соединения из настроек;
+	// пароль из неё не попадает ни в журнал, ни в ошибки
+	logger.Log.Info("Opening database", zap.String("dsn", pg.RedactDSN(cfg.DatabaseURI)))
+	conn, err := pg.Open(cfg.DatabaseURI)
+	if err != nil {
 		return err
 	}
 
-	// создаём соединение с СУБД PostgreSQL с помощью аргумента командной строки
-	conn, err := sql.Open("pgx", flagDatabaseURI)
+	// досоздаём недостающие таблицы до приёма запросов: без них обработчики падали бы на каждом запросе к хранилищу
+	pgStore := pg.NewStore(conn, logger.Log.Named("pg"))
+	bootstrapCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	err = pgStore.Bootstrap(bootstrapCtx)
+	cancel()
 	if err != nil {
 		return err
 	}
 
 	// создаём экземпляр приложения, передавая реализацию хранилища pg в качестве внешней зависимости
-	appInstance := newApp(pg.NewStore(conn))
+	// вызовы хранилища измеряются декоратором, чтобы метрики не зависели от реализации
+	appInstance := newApp(instrumented.NewStore(pgStore))
+	appInstance.timeout = cfg.ResponseTimeout
+	appInstance.strict = cfg.StrictJSON
+	appInstance.clientIPHeader = cfg.ClientIPHeader
+
+	limits, err := rateLimits(cfg.RateLimits)
+	if err != nil {
+		return err
+	}
+	appInstance.limiter = ratelimit.New(ratelimit.NewMemoryBuckets(), limits)
+
+	// подключаем внешнюю грамматику и следим за её изменениями
+	if cfg.GrammarFile != "" {
+		if err := appInstance.loadGrammar(cfg.GrammarFile); err != nil {
+			return err
+		}
+		appInstance.grammarFile = cfg.GrammarFile
+		go appInstance.watchGrammar(ctx, cfg.GrammarFile)
+	}
+
+	// диагностический сервер слушает отдельный адрес; ошибку занятого порта вернём сразу, а не из горутины
+	if cfg.AdminAddr != "" {
+		ln, err := net.Listen("tcp", cfg.AdminAddr)
+		if err != nil {
+			return err
+		}
+		logger.Log.Info("Running diagnostics server", zap.String("address", cfg.AdminAddr))
+		go func() {
+			if err := http.Serve(ln, appInstance.adminRoutes()); err != nil {
+				logger.Log.Error("diagnostics server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	logger.Log.Info("Running server", zap.String("address", cfg.RunAddr))
 
-	logger.Log.Info("Running server", zap.String("address", flagRunAddr))
+	mux := appInstance.routes(routesConfig{
+		auth:       newAuthConfig(cfg.SkillIDs, cfg.SecretHeader, cfg.Secret, cfg.TLSClientCA != ""),
+		body:       bodyLimits{maxBody: cfg.MaxBodySize, maxDecompressed: cfg.MaxDecompressed},
+		adminToken: cfg.AdminToken,
+		pprof:      cfg.Pprof,
+	})
+
+	// согласованность настроек TLS проверена в config.validate
+	if cfg.TLSCert == "" {
+		return http.ListenAndServe(cfg.RunAddr, mux)
+	}
 
-	// оборачиваем хендлер webhook в middleware с логированием и поддержкой gzip
-	return http.ListenAndServe(flagRunAddr, logger.RequestLogger(gzipMiddleware(appInstance.webhook)))
+	srv := &http.Server{Addr: cfg.RunAddr, Handler: mux}
+	if cfg.TLSClientCA != "" {
+		if srv.TLSConfig, err = newClientCertTLSConfig(cfg.TLSClientCA); err != nil {
+			return err
+		}
+	}
+	return srv.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
 }
 
 func gzipMiddleware(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// проверяем, что клиент отправил серверу сжатые данные в формате gzip; другие способы сжатия не поддерживаются
+		switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			metrics.GzipUsage.WithLabelValues("request").Inc()
+
+			// оборачиваем тело запроса  io.Reader с поддержкой декомпрессии
+			cr, err := newCompressReader(r.Body)
+			if err != nil {
+				// тело не является gzip-потоком или превышает ограничение размера
+				logger.FromContext(r.Context()).Debug("cannot decompress request body", zap.Error(err))
+				w.WriteHeader(bodyErrorStatus(err))
+				return
+			}
+
+			// меняем тело запроса на новое; его размер после распаковки заранее неизвестен
+			r.Body = cr
+			r.ContentLength = -1
+			defer cr.Close()
+		default:
+			logger.FromContext(r.Context()).Debug("unsupported request encoding", zap.String("encoding", r.Header.Get("Content-Encoding")))
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
 		// по умолчанию устанавливаем оригинальный http.ResponseWriter как тот, который будем передавать следующей функции
 		ow := w
 
@@ -49,6 +187,8 @@ func gzipMiddleware(h http.HandlerFunc) http.HandlerFunc {
 		acceptEncoding := r.Header.Get("Accept-Encoding")
 		supportsGzip := strings.Contains(acceptEncoding, "gzip")
 		if supportsGzip {
+			metrics.GzipUsage.WithLabelValues("response").Inc()
+
 			// оборачиваем оригинальный http.ResponseWriter новым с поддержкой сжатия
 			cw := newCompressWriter(w)
 
@@ -59,22 +199,6 @@ func gzipMiddleware(h http.HandlerFunc) http.HandlerFunc {
 			defer cw.Close()
 		}
 
-		// проверяем, что клиент отправил серверу сжатые данные в формате gzip
-		contentEncoding := r.Header.Get("Content-Encoding")
-		sendsGzip := strings.Contains(contentEncoding, "gzip")
-		if sendsGzip {
-			// оборачиваем тело запроса  io.Reader с поддержкой декомпрессии
-			cr, err := newCompressReader(r.Body)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			// меняем тело запроса на новое
-			r.Body = cr
-			defer cr.Close()
-		}
-
 		// передаём управление хендлеру
 		h.ServeHTTP(ow, r)
 	}
@@ -1,20 +1,32 @@
 package main
 
 import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/intent"
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/models"
 	"alice-skill/internal/store"
 	"alice-skill/internal/store/mock"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestWebhook(t *testing.T) {
@@ -33,6 +45,10 @@ func TestWebhook(t *testing.T) {
 
 	// устанавливаем условие: при любом вызове метода ListMessages возвращать массив messages без ошибки
 	s.EXPECT().ListMessages(gomock.Any(), gomock.Any()).Return(messages, nil)
+	// сработавших напоминаний у пользователя нет
+	s.EXPECT().ListReminders(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil)
+	// пользователь не менял настройки
+	s.EXPECT().GetSettings(gomock.Any(), gomock.Any()).Return(nil, store.ErrNotFound).AnyTimes()
 
 	// создадим экземлпяр приложения и передадим ему "хранилище"
 	appInstance := newApp(s)
@@ -77,14 +93,38 @@ func TestWebhook(t *testing.T) {
 		{
 			name:         "method_post_without_body",
 			method:       http.MethodPost,
-			expectedCode: http.StatusInternalServerError,
+			expectedCode: http.StatusBadRequest,
 			expectedBody: "",
 		},
 		{
-			name:         "method_post_unsupported_type",
+			name:         "method_post_unknown_type",
 			method:       http.MethodPost,
 			body:         `{"request": {"type": "idunno", "command": "do something"}, "version": "1.0"}`,
-			expectedCode: http.StatusUnprocessableEntity,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			// нажатие кнопки — корректный запрос, навык отвечает на него фразой, а не ошибкой
+			name:         "method_post_button_pressed",
+			method:       http.MethodPost,
+			body:         `{"request": {"type": "ButtonPressed", "payload": {}}, "version": "1.0"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: unsupportedText,
+		},
+		{
+			// проверка доступности от платформы не должна обращаться к хранилищу
+			name:         "method_post_ping",
+			method:       http.MethodPost,
+			body:         `{"request": {"type": "SimpleUtterance", "command": "ping", "original_utterance": "ping"}, "session": {"new": true}, "version": "1.0"}`,
+			expectedCode: http.StatusOK,
+			expectedBody: `"text":"pong"`,
+		},
+		{
+			// пустой запрос — не проверка доступности, а нарушение протокола
+			name:         "method_post_empty_object",
+			method:       http.MethodPost,
+			body:         `{}`,
+			expectedCode: http.StatusBadRequest,
 			expectedBody: "",
 		},
 		{
@@ -152,7 +192,9 @@ func TestGzipCompression(t *testing.T) {
 	}
 
 	// устанавливаем условие: при любом вызове метода ListMessages возвращать массив messages без ошибки
-	s.EXPECT().ListMessages(gomock.Any(), gomock.Any()).Return(messages, nil)
+	s.EXPECT().ListMessages(gomock.Any(), gomock.Any()).Return(messages, nil).AnyTimes()
+	s.EXPECT().ListReminders(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	s.EXPECT().GetSettings(gomock.Any(), gomock.Any()).Return(nil, store.ErrNotFound).AnyTimes()
 
 	// создадим экземлпяр приложения и передадим ему "хранилище"
 	appInstance := newApp(s)
@@ -220,3 +262,146 @@ func TestGzipCompression(t *testing.T) {
 		assert.Regexp(t, successBody, string(b))
 	})
 }
+
+func TestWebhookDeadline(t *testing.T) {
+	status := `{"request": {"type": "SimpleUtterance", "command": "sudo do something"}, "session": {"user_id": "user1"}, "version": "1.0"}`
+	remind := `{"request": {"type": "SimpleUtterance", "command": "напомни мне в 18:00 купить хлеб"}, "session": {"user_id": "user1"}, "version": "1.0"}`
+
+	testCases := []struct {
+		name string
+		body string
+		// expect имитирует медленное хранилище, которое отвечает только после закрытия release
+		expect func(s *mock.MockStore, release <-chan struct{})
+		want   string
+	}{
+		{
+			name: "store_respects_context",
+			body: status,
+			expect: func(s *mock.MockStore, _ <-chan struct{}) {
+				s.EXPECT().ListMessages(gomock.Any(), "user1").DoAndReturn(func(ctx context.Context, _ string) ([]store.Message, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				})
+			},
+			want: busyText,
+		},
+		{
+			name: "store_ignores_context",
+			body: status,
+			expect: func(s *mock.MockStore, release <-chan struct{}) {
+				s.EXPECT().ListMessages(gomock.Any(), "user1").DoAndReturn(func(context.Context, string) ([]store.Message, error) {
+					<-release
+					return nil, nil
+				})
+			},
+			want: busyText,
+		},
+		{
+			// напоминание могло сохраниться, поэтому повторять команду пользователю не предлагаем
+			name: "deadline_while_changing_data",
+			body: remind,
+			expect: func(s *mock.MockStore, release <-chan struct{}) {
+				s.EXPECT().SaveReminder(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, store.Reminder) error {
+					<-release
+					return nil
+				})
+			},
+			want: uncertainText,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			s := mock.NewMockStore(ctrl)
+			s.EXPECT().GetSettings(gomock.Any(), gomock.Any()).Return(nil, store.ErrNotFound)
+			release := make(chan struct{})
+			tc.expect(s, release)
+
+			appInstance := newApp(s)
+			appInstance.timeout = 20 * time.Millisecond
+
+			degraded := testutil.ToFloat64(metrics.DegradedResponses.WithLabelValues(metrics.ReasonDeadline))
+
+			// ответ приходит по истечении бюджета, пока хранилище ещё не ответило
+			w := httptest.NewRecorder()
+			appInstance.webhook(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+			close(release)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), tc.want)
+			assert.Equal(t, degraded+1, testutil.ToFloat64(metrics.DegradedResponses.WithLabelValues(metrics.ReasonDeadline)))
+		})
+	}
+}
+
+func TestBeginEffects(t *testing.T) {
+	effects := new(atomic.Int32)
+	ctx := context.WithValue(context.Background(), effectsKey{}, effects)
+
+	require.NoError(t, beginEffects(ctx))
+	assert.Equal(t, effectsStarted, effects.Load())
+	// обработчик может изменить несколько записей подряд
+	require.NoError(t, beginEffects(ctx))
+
+	// срок ответа истёк раньше, чем обработчик дошёл до изменения данных: пользователь уже услышал просьбу повторить
+	effects.Store(effectsExpired)
+	assert.ErrorIs(t, beginEffects(ctx), context.DeadlineExceeded)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, beginEffects(cancelled), context.Canceled)
+}
+
+func TestHandleSendQueueFull(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mock.NewMockStore(ctrl)
+	s.EXPECT().FindRecipient(gomock.Any(), "ivan").Return("user2", nil)
+
+	// очередь без буфера и без читателя: сообщение поставить некуда
+	a := &app{store: s, msgChan: make(chan queuedMessage)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	turn := &intent.Turn{
+		Request: &models.Request{Session: models.Session{User: models.User{UserID: "user1"}}},
+		Slots:   map[string]string{"recipient": "ivan", "text": "привет"},
+	}
+	_, err := a.handleSend(ctx, turn)
+
+	// обработчик возвращается по истечении срока, а не ждёт место в очереди
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, apperr.From(err).Retryable)
+}
+
+func TestSaveBatchLinks(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctrl := gomock.NewController(t)
+	s := mock.NewMockStore(ctrl)
+	s.EXPECT().SaveMessages(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	// два запроса поставили сообщения в очередь, третье сообщение пришло без трассы
+	_, first := provider.Tracer("test").Start(context.Background(), "first")
+	_, second := provider.Tracer("test").Start(context.Background(), "second")
+	batch := []queuedMessage{
+		{Message: store.Message{Payload: "раз"}, origin: first.SpanContext()},
+		{Message: store.Message{Payload: "два"}, origin: second.SpanContext()},
+		{Message: store.Message{Payload: "три"}},
+	}
+
+	a := &app{store: s}
+	require.NoError(t, a.saveBatch(context.Background(), batch))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "flushMessages", spans[0].Name())
+	// пачка сохраняется в собственной трассе, связанной с трассами запросов
+	assert.False(t, spans[0].Parent().IsValid())
+	require.Len(t, spans[0].Links(), 2)
+	assert.Equal(t, first.SpanContext().TraceID(), spans[0].Links()[0].SpanContext.TraceID())
+	assert.Equal(t, second.SpanContext().TraceID(), spans[0].Links()[1].SpanContext.TraceID())
+}
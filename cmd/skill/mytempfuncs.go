@@ -1,13 +0,0 @@
-package main
-
-func parseSendCommand(com string) (string, string) {
-	return "username1", "message1"
-}
-
-func parseReadCommand(com string) int {
-	return 1
-}
-
-func parseRegisterCommand(com string) string {
-	return "username1"
-}
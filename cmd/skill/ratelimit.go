@@ -0,0 +1,76 @@
+package main
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/logger"
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/ratelimit"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ответ пользователю, который отправляет команды чаще, чем разрешено
+const rateLimitedText = "Слишком много сообщений, подождите."
+
+// ограничения по умолчанию: отправка сообщений другим пользователям строже остальных команд.
+// Ограничение на IP-адрес действует, только если задан заголовок с адресом клиента, см. clientIP;
+// адрес приходит через прокси, за которым могут быть несколько пользователей, поэтому ограничение мягкое.
+var defaultRateLimits = map[string]ratelimit.Limit{
+	intentSend:           {Rate: 10.0 / 60, Burst: 5},
+	ratelimit.KeyDefault: {Rate: 1, Burst: 20},
+	ratelimit.KeyIP:      {Rate: 100, Burst: 200},
+}
+
+// limited ограничивает частоту команд интента name; при превышении пользователь слышит просьбу подождать
+func (a *app) limited(name string, h intent.Handler) intent.Handler {
+	return func(ctx context.Context, t *intent.Turn) (string, error) {
+		ok, err := a.limiter.Allow(ctx, name, t.Request.Session.Identity(), t.ClientIP)
+		if err != nil {
+			// если состояние ограничений недоступно, не мешаем пользователям
+			logger.FromContext(ctx).Warn("cannot check rate limit", zap.String("intent", name), zap.Error(err))
+		} else if !ok {
+			logger.FromContext(ctx).Debug("rate limit exceeded", zap.String("intent", name))
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			return rateLimitedText, nil
+		}
+		return h(ctx, t)
+	}
+}
+
+// rateLimits дополняет ограничения по умолчанию заданными в spec
+func rateLimits(spec string) (map[string]ratelimit.Limit, error) {
+	custom, err := ratelimit.ParseLimits(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]ratelimit.Limit, len(defaultRateLimits)+len(custom))
+	for name, limit := range defaultRateLimits {
+		limits[name] = limit
+	}
+	for name, limit := range custom {
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// clientIP возвращает адрес клиента из заголовка header, который выставляет доверенный прокси перед навыком.
+// Адрес соединения для этого не годится: запросы приходят с серверов Алисы или от прокси, и ограничение
+// на него стало бы общим для всех пользователей. Поэтому без заголовка адрес неизвестен и не ограничивается.
+// В списке вида X-Forwarded-For берётся последний адрес — его добавил ближайший к навыку прокси.
+func clientIP(r *http.Request, header string) string {
+	if header == "" {
+		return ""
+	}
+
+	values := strings.Split(r.Header.Get(header), ",")
+	ip := net.ParseIP(strings.TrimSpace(values[len(values)-1]))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"alice-skill/internal/ratelimit"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimited(t *testing.T) {
+	a := &app{limiter: ratelimit.New(ratelimit.NewMemoryBuckets(), map[string]ratelimit.Limit{
+		intentSend: {Rate: 0.001, Burst: 1},
+	})}
+
+	calls := 0
+	h := a.limited(intentSend, func(context.Context, *intent.Turn) (string, error) {
+		calls++
+		return "Отправлено", nil
+	})
+
+	turn := &intent.Turn{Request: &models.Request{Session: models.Session{User: models.User{UserID: "user1"}}}}
+
+	text, err := h(context.Background(), turn)
+	require.NoError(t, err)
+	assert.Equal(t, "Отправлено", text)
+
+	// превышение ограничения — не ошибка: пользователь слышит просьбу подождать, а обработчик не вызывается
+	text, err = h(context.Background(), turn)
+	require.NoError(t, err)
+	assert.Equal(t, rateLimitedText, text)
+	assert.Equal(t, 1, calls)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{name: "без заголовка адрес соединения не используется", header: "", value: "203.0.113.7", want: ""},
+		{name: "адрес из заголовка", header: "X-Real-IP", value: "203.0.113.7", want: "203.0.113.7"},
+		{name: "последний адрес в списке прокси", header: "X-Forwarded-For", value: "198.51.100.1, 203.0.113.7", want: "203.0.113.7"},
+		{name: "заголовок не передан", header: "X-Real-IP", value: "", want: ""},
+		{name: "не адрес", header: "X-Real-IP", value: "unknown", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.RemoteAddr = "10.0.0.1:51234"
+			if tt.value != "" {
+				r.Header.Set("X-Real-IP", tt.value)
+				r.Header.Set("X-Forwarded-For", tt.value)
+			}
+			assert.Equal(t, tt.want, clientIP(r, tt.header))
+		})
+	}
+}
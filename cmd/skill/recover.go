@@ -0,0 +1,73 @@
+package main
+
+import (
+	"alice-skill/internal/apperr"
+	"alice-skill/internal/logger"
+	"alice-skill/internal/metrics"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// recovered пишет в журнал значение паники rec со стеком вызовов, учитывает её в метриках
+// и возвращает внутреннюю ошибку, на которую пользователь услышит извинение
+func recovered(ctx context.Context, rec any) error {
+	logger.FromContext(ctx).Error("panic while handling request",
+		zap.Any("panic", rec),
+		zap.ByteString("stack", debug.Stack()),
+	)
+	metrics.Panics.Inc()
+	metrics.DegradedResponses.WithLabelValues(metrics.ReasonPanic).Inc()
+	logger.Annotate(ctx, zap.String("degraded", metrics.ReasonPanic))
+
+	return apperr.Internal(fmt.Errorf("panic: %v", rec))
+}
+
+// recoverPanics перехватывает панику обработчика h и, если ответ ещё не начат, отвечает Алисе извинением.
+// Без этого соединение обрывается, и Алиса сообщает, что навык не отвечает.
+func recoverPanics(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoveryWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.ErrAbortHandler — штатный способ прервать ответ, его обрабатывает сам net/http
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			ctx := r.Context()
+			err := recovered(ctx, rec)
+
+			// часть ответа уже отправлена, дописать к ней корректный JSON нельзя
+			if rw.wroteHeader {
+				return
+			}
+			writeResponse(ctx, w, errorResponse(ctx, err))
+		}()
+
+		h(rw, r)
+	}
+}
+
+// recoveryWriter запоминает, начал ли обработчик отправлять ответ
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
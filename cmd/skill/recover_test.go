@@ -0,0 +1,85 @@
+package main
+
+import (
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	testCases := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantApology bool
+	}{
+		{
+			name: "panic_before_response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				var req *models.Request
+				_ = req.Session.SessionID
+			},
+			wantApology: true,
+		},
+		{
+			name: "panic_after_response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("late")
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			panics := testutil.ToFloat64(metrics.Panics)
+
+			w := httptest.NewRecorder()
+			recoverPanics(tc.handler)(w, httptest.NewRequest(http.MethodPost, "/alice/webhook", nil))
+
+			assert.Equal(t, panics+1, testutil.ToFloat64(metrics.Panics))
+			if !tc.wantApology {
+				assert.Equal(t, http.StatusAccepted, w.Code)
+				assert.Empty(t, w.Body.String())
+				return
+			}
+
+			// Алиса получает корректный ответ, и диалог продолжается
+			assert.Equal(t, http.StatusOK, w.Code)
+			var resp models.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, internalErrorText, resp.Response.Text)
+		})
+	}
+}
+
+func TestWebhookRecoversIntentPanic(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mock.NewMockStore(ctrl)
+	s.EXPECT().GetSettings(gomock.Any(), "user1").DoAndReturn(func(context.Context, string) (*store.Settings, error) {
+		panic("broken store")
+	})
+
+	appInstance := newApp(s)
+	panics := testutil.ToFloat64(metrics.Panics)
+
+	// реплика обрабатывается в отдельной горутине, паника в ней не должна завершать процесс
+	body := `{"request": {"type": "SimpleUtterance", "command": "прочитай"}, "session": {"user_id": "user1"}, "version": "1.0"}`
+	w := httptest.NewRecorder()
+	appInstance.webhook(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), internalErrorText)
+	assert.Equal(t, panics+1, testutil.ToFloat64(metrics.Panics))
+}
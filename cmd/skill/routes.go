@@ -0,0 +1,92 @@
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"alice-skill/internal/metrics"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.uber.org/zap"
+)
+
+// заголовок, в котором передаётся токен доступа к административным маршрутам
+const adminTokenHeader = "X-Admin-Token"
+
+// в журнал доступа попадает каждый probeLogSampling-й успешный запрос проверок и метрик
+const probeLogSampling = 100
+
+// routesConfig описывает, какие маршруты и с какими проверками обслуживает сервер
+type routesConfig struct {
+	auth       authConfig // проверка подлинности запросов Алисы
+	body       bodyLimits // ограничения размера тела запросов к webhook; нулевые значения заменяются значениями по умолчанию
+	adminToken string     // токен административных маршрутов; пустая строка — маршруты отключены
+	pprof      bool       // отдавать профили runtime/pprof
+}
+
+// routes возвращает маршрутизатор навыка; у каждого маршрута своя цепочка middleware, неизвестные пути получают 404
+func (a *app) routes(cfg routesConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	// webhook навыка: трасса, журнал, метрики, gzip, перехват паник и проверка подлинности, которая читает уже
+	// распакованное тело; спан запроса открывается первым, чтобы в него попали все остальные этапы, а извинение
+	// после паники проходит через gzip, журнал и метрики как обычный ответ.
+	// Размер тела ограничивается дважды: до распаковки и после неё, чтобы маленький сжатый запрос не развернулся в гигабайты.
+	limits := cfg.body.orDefaults()
+	webhook := otelhttp.NewHandler(
+		logger.RequestLogger(metrics.Instrument(limitBody(limits.maxBody)(gzipMiddleware(limitBody(limits.maxDecompressed)(
+			recoverPanics(authMiddleware(cfg.auth)(a.webhook)),
+		))))),
+		"alice.webhook",
+	)
+	mux.Handle("/alice/webhook", webhook)
+	// версия протокола в пути позволит однажды обслуживать две версии одновременно
+	mux.Handle("/alice/v1/webhook", webhook)
+
+	// проверки живости и готовности и сбор метрик вызываются часто, поэтому в журнал попадает лишь часть из них
+	mux.Handle("/healthz", logger.SampledRequestLogger(probeLogSampling, a.healthz))
+	mux.Handle("/readyz", logger.SampledRequestLogger(probeLogSampling, a.readyz))
+	mux.Handle("/metrics", logger.SampledRequestLogger(probeLogSampling, promhttp.Handler().ServeHTTP))
+
+	if cfg.adminToken != "" {
+		admin := authMiddleware(newAuthConfig("", adminTokenHeader, cfg.adminToken, false))
+		mux.Handle("/admin/grammar/reload", logger.RequestLogger(admin(a.reloadGrammar)))
+		mux.Handle("/admin/loglevel", logger.RequestLogger(admin(logger.Level.ServeHTTP)))
+	}
+
+	// на публичном адресе профили доступны только по явному флагу, обычно их смотрят через диагностический сервер
+	if cfg.pprof {
+		registerPprof(mux)
+	}
+
+	// идентификатор запроса назначается до всех остальных middleware, чтобы попасть в каждую строку журнала
+	return logger.RequestID(mux)
+}
+
+// reloadGrammar перечитывает файл грамматики, не дожидаясь, пока изменение заметит watchGrammar
+func (a *app) reloadGrammar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if a.grammarFile == "" {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "grammar file is not configured, the built-in grammar is used")
+		return
+	}
+
+	// ошибки грамматики возвращаем целиком, чтобы их можно было исправить за один раз
+	if err := a.loadGrammar(a.grammarFile); err != nil {
+		logger.FromContext(r.Context()).Error("cannot reload grammar, keeping the previous one", zap.String("path", a.grammarFile), zap.Error(err))
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("grammar reloaded", zap.String("path", a.grammarFile))
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"alice-skill/internal/store/mock"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mock.NewMockStore(ctrl)
+	appInstance := newApp(s)
+
+	path := filepath.Join(t.TempDir(), "grammar.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("intents:\n  send:\n    - отправь {recipient:name} {text:any}\n"), 0o600))
+	appInstance.grammarFile = path
+
+	ping := `{"request": {"type": "SimpleUtterance", "command": "ping", "original_utterance": "ping"}, "version": "1.0"}`
+
+	testCases := []struct {
+		name         string
+		cfg          routesConfig
+		method       string
+		path         string
+		body         string
+		header       http.Header
+		expectedCode int
+	}{
+		{
+			name:         "webhook",
+			method:       http.MethodPost,
+			path:         "/alice/webhook",
+			body:         ping,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "versioned_webhook",
+			method:       http.MethodPost,
+			path:         "/alice/v1/webhook",
+			body:         ping,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "webhook_checks_skill_id",
+			cfg:          routesConfig{auth: newAuthConfig("skill1", "", "", false)},
+			method:       http.MethodPost,
+			path:         "/alice/webhook",
+			body:         ping,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "root_is_not_webhook",
+			method:       http.MethodPost,
+			path:         "/",
+			body:         ping,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "unknown_path",
+			method:       http.MethodGet,
+			path:         "/wp-login.php",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "healthz",
+			method:       http.MethodGet,
+			path:         "/healthz",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "metrics",
+			method:       http.MethodGet,
+			path:         "/metrics",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "pprof_disabled",
+			method:       http.MethodGet,
+			path:         "/debug/pprof/",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "pprof_enabled",
+			cfg:          routesConfig{pprof: true},
+			method:       http.MethodGet,
+			path:         "/debug/pprof/",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin_disabled",
+			method:       http.MethodPost,
+			path:         "/admin/grammar/reload",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "admin_without_token",
+			cfg:          routesConfig{adminToken: "t0ken"},
+			method:       http.MethodPost,
+			path:         "/admin/grammar/reload",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "admin_grammar_reload",
+			cfg:          routesConfig{adminToken: "t0ken"},
+			method:       http.MethodPost,
+			path:         "/admin/grammar/reload",
+			header:       http.Header{adminTokenHeader: {"t0ken"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin_loglevel",
+			cfg:          routesConfig{adminToken: "t0ken"},
+			method:       http.MethodGet,
+			path:         "/admin/loglevel",
+			header:       http.Header{adminTokenHeader: {"t0ken"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin_loglevel_without_token",
+			cfg:          routesConfig{adminToken: "t0ken"},
+			method:       http.MethodPut,
+			path:         "/admin/loglevel",
+			body:         `{"level":"debug"}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				r.Header[k] = v
+			}
+
+			w := httptest.NewRecorder()
+			appInstance.routes(tc.cfg).ServeHTTP(w, r)
+			assert.Equal(t, tc.expectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"alice-skill/internal/store"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// часовые пояса, которые пользователь может назвать голосом
+var timezonesByCity = map[string]string{
+	"калининград":  "Europe/Kaliningrad",
+	"москва":       "Europe/Moscow",
+	"самара":       "Europe/Samara",
+	"екатеринбург": "Asia/Yekaterinburg",
+	"омск":         "Asia/Omsk",
+	"новосибирск":  "Asia/Novosibirsk",
+	"красноярск":   "Asia/Krasnoyarsk",
+	"иркутск":      "Asia/Irkutsk",
+	"якутск":       "Asia/Yakutsk",
+	"владивосток":  "Asia/Vladivostok",
+	"магадан":      "Asia/Magadan",
+	"камчатка":     "Asia/Kamchatka",
+}
+
+// loadSettings возвращает настройки пользователя, подставляя значения по умолчанию, если он их не менял
+func (a *app) loadSettings(ctx context.Context, userID string) (store.Settings, error) {
+	settings, err := a.store.GetSettings(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.DefaultSettings(userID), nil
+	}
+	if err != nil {
+		return store.Settings{}, err
+	}
+	return *settings, nil
+}
+
+// userLocation определяет часовой пояс пользователя: сначала из настроек, затем из запроса, иначе UTC
+func userLocation(settings store.Settings, reqTimezone string) *time.Location {
+	for _, name := range []string{settings.Timezone, reqTimezone} {
+		if name == "" {
+			continue
+		}
+		if tz, err := time.LoadLocation(name); err == nil {
+			return tz
+		}
+	}
+	return time.UTC
+}
+
+// pick выбирает вариант ответа в соответствии с предпочитаемой подробностью
+func pick(settings store.Settings, long, short string) string {
+	if settings.Verbosity == store.VerbosityShort {
+		return short
+	}
+	return long
+}
+
+// sortMessages упорядочивает сообщения в соответствии с предпочитаемым порядком чтения
+func sortMessages(settings store.Settings, messages []store.Message) {
+	sort.SliceStable(messages, func(i, j int) bool {
+		if settings.ReadOrder == store.ReadOrderNewest {
+			return messages[i].Time.After(messages[j].Time)
+		}
+		return messages[i].Time.Before(messages[j].Time)
+	})
+}
+
+// applySettingsCommand изменяет настройки согласно голосовой команде и возвращает текст ответа
+// той подробности, которая действует после изменения.
+// Второе значение сообщает, изменились ли настройки: если команда не распознана или названо неизвестное значение,
+// отвечать нужно без сохранения.
+func applySettingsCommand(settings *store.Settings, command string) (string, bool) {
+	cmd := strings.ToLower(strings.TrimSpace(command))
+
+	switch {
+	case strings.HasPrefix(cmd, "не называй время"):
+		settings.AnnounceTime = false
+		return pick(*settings, "Хорошо, не буду называть время при входе.", "Не буду называть время."), true
+
+	case strings.HasPrefix(cmd, "называй время"):
+		settings.AnnounceTime = true
+		return pick(*settings, "Хорошо, буду называть время при входе.", "Буду называть время."), true
+
+	case strings.HasPrefix(cmd, "отвечай"):
+		switch {
+		case strings.Contains(cmd, "кратко"), strings.Contains(cmd, "коротко"):
+			settings.Verbosity = store.VerbosityShort
+			return pick(*settings, "Поняла, буду краткой.", "Поняла."), true
+		case strings.Contains(cmd, "подробно"):
+			settings.Verbosity = store.VerbosityLong
+			return pick(*settings, "Хорошо, буду отвечать подробно.", "Буду отвечать подробно."), true
+		}
+
+	case strings.HasPrefix(cmd, "читай сначала"):
+		switch {
+		case strings.Contains(cmd, "нов"):
+			settings.ReadOrder = store.ReadOrderNewest
+			return pick(*settings, "Хорошо, буду читать сначала новые сообщения.", "Сначала новые."), true
+		case strings.Contains(cmd, "стар"):
+			settings.ReadOrder = store.ReadOrderOldest
+			return pick(*settings, "Хорошо, буду читать сначала старые сообщения.", "Сначала старые."), true
+		}
+
+	case strings.HasPrefix(cmd, "мой часовой пояс"):
+		city := strings.TrimSpace(strings.TrimPrefix(cmd, "мой часовой пояс"))
+		if city == "как на устройстве" {
+			settings.Timezone = ""
+			return pick(*settings, "Хорошо, буду использовать часовой пояс устройства.", "Пояс как на устройстве."), true
+		}
+		if tz, ok := timezonesByCity[city]; ok {
+			settings.Timezone = tz
+			return pick(*settings, "Запомнила ваш часовой пояс.", "Запомнила."), true
+		}
+		return pick(*settings,
+			"Не знаю такого часового пояса. Назовите крупный город, например Москва или Новосибирск.",
+			"Не знаю такого города.",
+		), false
+	}
+
+	return pick(*settings, "Не поняла, какую настройку изменить.", "Не поняла."), false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySettingsCommand(t *testing.T) {
+	testCases := []struct {
+		name        string
+		command     string
+		want        store.Settings
+		wantChanged bool
+	}{
+		{
+			name:        "disable_time",
+			command:     "Не называй время при входе",
+			want:        store.Settings{AnnounceTime: false, Verbosity: store.VerbosityLong, ReadOrder: store.ReadOrderOldest},
+			wantChanged: true,
+		},
+		{
+			name:        "short_replies",
+			command:     "Отвечай кратко",
+			want:        store.Settings{AnnounceTime: true, Verbosity: store.VerbosityShort, ReadOrder: store.ReadOrderOldest},
+			wantChanged: true,
+		},
+		{
+			name:        "newest_first",
+			command:     "Читай сначала новые",
+			want:        store.Settings{AnnounceTime: true, Verbosity: store.VerbosityLong, ReadOrder: store.ReadOrderNewest},
+			wantChanged: true,
+		},
+		{
+			name:        "timezone_by_city",
+			command:     "Мой часовой пояс Новосибирск",
+			want:        store.Settings{Timezone: "Asia/Novosibirsk", AnnounceTime: true, Verbosity: store.VerbosityLong, ReadOrder: store.ReadOrderOldest},
+			wantChanged: true,
+		},
+		{
+			// неизвестный город не должен сбрасывать настроенный часовой пояс
+			name:        "unknown_timezone",
+			command:     "Мой часовой пояс Атлантида",
+			want:        store.DefaultSettings(""),
+			wantChanged: false,
+		},
+		{
+			name:        "unknown",
+			command:     "Отвечай шёпотом",
+			want:        store.DefaultSettings(""),
+			wantChanged: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := store.DefaultSettings("")
+			_, changed := applySettingsCommand(&settings, tc.command)
+			assert.Equal(t, tc.wantChanged, changed)
+			assert.Equal(t, tc.want, settings)
+		})
+	}
+}
+
+func TestUserLocation(t *testing.T) {
+	settings := store.DefaultSettings("")
+	assert.Equal(t, "Europe/Moscow", userLocation(settings, "Europe/Moscow").String())
+	assert.Equal(t, time.UTC, userLocation(settings, "Not/AZone"))
+
+	settings.Timezone = "Asia/Omsk"
+	assert.Equal(t, "Asia/Omsk", userLocation(settings, "Europe/Moscow").String())
+}
+
+func TestHandleSettingsUnknownTimezone(t *testing.T) {
+	// ни одного вызова хранилища: неизвестное значение не сохраняется
+	ctrl := gomock.NewController(t)
+	a := newApp(mock.NewMockStore(ctrl))
+
+	turn := &intent.Turn{
+		Request:  &models.Request{Request: models.SimpleUtterance{Command: "Мой часовой пояс Атлантида"}},
+		Settings: store.DefaultSettings("user1"),
+	}
+	reply, err := a.handleSettings(context.Background(), turn)
+	require.NoError(t, err)
+	assert.Contains(t, reply, "Не знаю такого часового пояса")
+}
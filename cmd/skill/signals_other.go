@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// watchLogLevelSignals ничего не делает: без SIGUSR1 и SIGHUP уровень журнала меняется только через диагностический сервер
+func watchLogLevelSignals() {}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package main
+
+import (
+	"alice-skill/internal/logger"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// watchLogLevelSignals меняет уровень журнала по сигналам: SIGUSR1 включает и выключает debug,
+// SIGHUP возвращает уровень, заданный при запуске
+func watchLogLevelSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGHUP)
+
+	go func() {
+		for sig := range signals {
+			lvl := logger.ResetLevel
+			if sig == syscall.SIGUSR1 {
+				lvl = logger.ToggleDebug
+			}
+			// пишем на уровне warn, чтобы запись попала в журнал при любом уровне, кроме error
+			logger.Log.Warn("log level changed", zap.Stringer("signal", sig), zap.Stringer("level", lvl()))
+		}
+	}()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// stateKey — ключ, под которым состояние диалога хранится в состояниях Алисы
+const stateKey = "dialog"
+
+// dialogState описывает небольшие пользовательские данные, которые нужно помнить между репликами
+type dialogState struct {
+	LastReadIndex int `json:"last_read_index,omitempty"` // номер последнего прочитанного сообщения
+}
+
+// stateStorage описывает место хранения состояния диалога
+type stateStorage interface {
+	// Load возвращает сохранённое состояние или пустое, если его ещё нет
+	Load(ctx context.Context) (dialogState, error)
+	// Save сохраняет состояние, при необходимости дополняя ответ навыка
+	Save(ctx context.Context, resp *models.Response, st dialogState) error
+}
+
+// stateFor выбирает хранилище состояния для запроса:
+// состояние пользователя Алисы, состояние приложения Алисы или, если Алиса не передала ни одного, хранилище навыка
+func (a *app) stateFor(req *models.Request) stateStorage {
+	if req.State != nil && req.State.User != nil {
+		return aliceUserState{state: req.State.User}
+	}
+	if req.State != nil && req.State.Application != nil {
+		return aliceApplicationState{state: req.State.Application}
+	}
+	return storeState{store: a.store, userID: req.Session.Identity()}
+}
+
+// aliceUserState хранит состояние в user_state_update, доступном авторизованным пользователям
+type aliceUserState struct {
+	state map[string]any
+}
+
+func (s aliceUserState) Load(_ context.Context) (dialogState, error) {
+	return decodeState(s.state[stateKey])
+}
+
+func (s aliceUserState) Save(_ context.Context, resp *models.Response, st dialogState) error {
+	// Алиса применяет только изменённые ключи, поэтому передаём лишь свой
+	resp.UserStateUpdate = map[string]any{stateKey: st}
+	return nil
+}
+
+// aliceApplicationState хранит состояние в application_state, привязанном к экземпляру приложения
+type aliceApplicationState struct {
+	state map[string]any
+}
+
+func (s aliceApplicationState) Load(_ context.Context) (dialogState, error) {
+	return decodeState(s.state[stateKey])
+}
+
+func (s aliceApplicationState) Save(_ context.Context, resp *models.Response, st dialogState) error {
+	// состояние приложения перезаписывается целиком, поэтому сохраняем чужие ключи
+	state := make(map[string]any, len(s.state)+1)
+	for k, v := range s.state {
+		state[k] = v
+	}
+	state[stateKey] = st
+	resp.ApplicationState = state
+	return nil
+}
+
+// storeState хранит состояние в хранилище навыка
+type storeState struct {
+	store  store.Store
+	userID string
+}
+
+func (s storeState) Load(ctx context.Context) (dialogState, error) {
+	var st dialogState
+
+	data, err := s.store.GetUserState(ctx, s.userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
+
+func (s storeState) Save(ctx context.Context, _ *models.Response, st dialogState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return s.store.SaveUserState(ctx, s.userID, data)
+}
+
+// decodeState преобразует произвольное значение из состояния Алисы в dialogState
+func decodeState(v any) (dialogState, error) {
+	var st dialogState
+	if v == nil {
+		return st, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(data, &st)
+	return st, err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("alice_user_state", func(t *testing.T) {
+		a := &app{}
+		req := &models.Request{State: &models.State{
+			User: map[string]any{stateKey: map[string]any{"last_read_index": float64(2)}},
+		}}
+
+		states := a.stateFor(req)
+		st, err := states.Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, dialogState{LastReadIndex: 2}, st)
+
+		var resp models.Response
+		st.LastReadIndex = 3
+		require.NoError(t, states.Save(ctx, &resp, st))
+		assert.Equal(t, map[string]any{stateKey: dialogState{LastReadIndex: 3}}, resp.UserStateUpdate)
+		assert.Nil(t, resp.ApplicationState)
+	})
+
+	t.Run("alice_application_state_keeps_foreign_keys", func(t *testing.T) {
+		a := &app{}
+		req := &models.Request{State: &models.State{
+			Application: map[string]any{"other": "value"},
+		}}
+
+		states := a.stateFor(req)
+		st, err := states.Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, dialogState{}, st)
+
+		var resp models.Response
+		st.LastReadIndex = 1
+		require.NoError(t, states.Save(ctx, &resp, st))
+		assert.Equal(t, "value", resp.ApplicationState["other"])
+		assert.Equal(t, dialogState{LastReadIndex: 1}, resp.ApplicationState[stateKey])
+
+		// сохранённое состояние приложения Алиса вернёт в следующем запросе уже в виде JSON
+		data, err := json.Marshal(resp.ApplicationState)
+		require.NoError(t, err)
+		next := &models.Request{State: &models.State{}}
+		require.NoError(t, json.Unmarshal(data, &next.State.Application))
+		st, err = a.stateFor(next).Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, dialogState{LastReadIndex: 1}, st)
+	})
+
+	t.Run("store_fallback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		s := mock.NewMockStore(ctrl)
+		gomock.InOrder(
+			s.EXPECT().GetUserState(gomock.Any(), "user1").Return(nil, store.ErrNotFound),
+			s.EXPECT().SaveUserState(gomock.Any(), "user1", []byte(`{"last_read_index":1}`)).Return(nil),
+			s.EXPECT().GetUserState(gomock.Any(), "user1").Return([]byte(`{"last_read_index":1}`), nil),
+		)
+
+		a := &app{store: s}
+		req := &models.Request{Session: models.Session{User: models.User{UserID: "user1"}}}
+
+		states := a.stateFor(req)
+		st, err := states.Load(ctx)
+		require.NoError(t, err)
+
+		var resp models.Response
+		st.LastReadIndex = 1
+		require.NoError(t, states.Save(ctx, &resp, st))
+		assert.Nil(t, resp.UserStateUpdate)
+
+		st, err = states.Load(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, dialogState{LastReadIndex: 1}, st)
+	})
+}
@@ -0,0 +1,66 @@
+// Package apperr описывает ошибки обработки реплик: что услышит пользователь и поможет ли ему повтор команды.
+// Обработчики возвращают *Error там, где знают, как объяснить ошибку; остальные ошибки классифицирует From.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// Error — ошибка обработки реплики
+type Error struct {
+	Err       error  // исходная ошибка, попадает только в журнал
+	Phrase    string // фраза для пользователя; пустая у внутренних ошибок, вместо неё звучит общая фраза
+	Retryable bool   // ошибка временная, повтор той же команды может помочь
+}
+
+// User возвращает ошибку, которую пользователь может исправить сам; phrase объясняет, что не так
+func User(phrase string, err error) *Error {
+	return &Error{Err: err, Phrase: phrase}
+}
+
+// Internal возвращает внутреннюю ошибку навыка, подробности которой пользователю не сообщаются
+func Internal(err error) *Error {
+	return &Error{Err: err}
+}
+
+// Temporary возвращает внутреннюю ошибку, после которой команду стоит повторить, например из-за недоступности хранилища
+func Temporary(err error) *Error {
+	return &Error{Err: err, Retryable: true}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Phrase
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// UserFacing сообщает, что фраза ошибки объясняет пользователю, что не так
+func (e *Error) UserFacing() bool {
+	return e.Phrase != ""
+}
+
+// From возвращает *Error из цепочки err, а если его нет — классифицирует err как внутреннюю ошибку.
+// Истёкший срок и сетевые ошибки считаются временными. Для nil возвращает nil.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return Temporary(err)
+	}
+	return Internal(err)
+}
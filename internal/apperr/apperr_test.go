@@ -0,0 +1,49 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("boom")
+
+	testCases := []struct {
+		name          string
+		err           error
+		wantPhrase    string
+		wantRetryable bool
+	}{
+		{name: "plain_error", err: cause},
+		{name: "deadline", err: fmt.Errorf("cannot load: %w", context.DeadlineExceeded), wantRetryable: true},
+		{name: "network", err: fmt.Errorf("cannot load: %w", &net.OpError{Op: "dial", Err: cause}), wantRetryable: true},
+		{name: "user", err: fmt.Errorf("handler: %w", User("Нет такого пользователя.", cause)), wantPhrase: "Нет такого пользователя."},
+		{name: "temporary", err: Temporary(cause), wantRetryable: true},
+		{name: "internal_wins_over_deadline", err: Internal(context.DeadlineExceeded)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := From(tc.err)
+			assert.Equal(t, tc.wantPhrase, e.Phrase)
+			assert.Equal(t, tc.wantPhrase != "", e.UserFacing())
+			assert.Equal(t, tc.wantRetryable, e.Retryable)
+		})
+	}
+
+	assert.Nil(t, From(nil))
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	cause := errors.New("boom")
+
+	// исходная ошибка остаётся доступной для журнала и errors.Is
+	err := fmt.Errorf("handler: %w", User("Нет такого пользователя.", cause))
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "handler: boom", err.Error())
+}
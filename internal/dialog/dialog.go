@@ -0,0 +1,210 @@
+// Package dialog ведёт многошаговые диалоги: если в реплике не хватает обязательных слотов,
+// навык задаёт уточняющие вопросы и помнит уже собранные значения до конца сессии.
+// Собранные значения хранятся в состоянии сессии Алисы, поэтому диалог продолжается на любом экземпляре навыка.
+package dialog
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/store"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTTL — время, через которое незавершённый диалог забывается
+const DefaultTTL = 10 * time.Minute
+
+// frameKey — ключ, под которым незавершённый диалог хранится в состоянии сессии Алисы
+const frameKey = "dialog_frame"
+
+// фразы, которыми пользователь прерывает уточнение на любом шаге
+var cancelWords = map[string]bool{
+	"отмена":   true,
+	"отмени":   true,
+	"отменить": true,
+	"стоп":     true,
+	"хватит":   true,
+}
+
+// Frame описывает незавершённый диалог: интент, собранные слоты и слот, о котором навык спросил
+type Frame struct {
+	Intent    string            `json:"intent"`          // имя интента, ради которого ведётся диалог
+	Slots     map[string]string `json:"slots,omitempty"` // уже известные значения слотов
+	Awaiting  string            `json:"awaiting"`        // слот, значение которого ожидается в следующей реплике
+	UpdatedAt time.Time         `json:"updated_at"`      // время последнего шага
+}
+
+// Frames описывает хранилище незавершённых диалогов сессии, к которой относится ход
+type Frames interface {
+	// Get возвращает диалог сессии, если он есть
+	Get(t *intent.Turn) (Frame, bool)
+	// Put сохраняет диалог сессии
+	Put(t *intent.Turn, f Frame)
+	// Delete забывает диалог сессии
+	Delete(t *intent.Turn)
+}
+
+// Manager направляет реплики либо в продолжение незавершённого диалога, либо в роутер интентов
+type Manager struct {
+	router atomic.Pointer[intent.Router]
+	frames Frames
+}
+
+// NewManager создаёт менеджер диалогов поверх роутера интентов
+func NewManager(router *intent.Router, frames Frames) *Manager {
+	m := &Manager{frames: frames}
+	m.router.Store(router)
+	return m
+}
+
+// SetRouter заменяет роутер интентов, например после перезагрузки грамматики.
+// Незавершённые диалоги сохраняются и продолжаются с новым роутером.
+func (m *Manager) SetRouter(router *intent.Router) {
+	m.router.Store(router)
+}
+
+// Handle обрабатывает реплику хода t и возвращает текст ответа
+func (m *Manager) Handle(ctx context.Context, t *intent.Turn) (string, error) {
+	frame, active := m.frames.Get(t)
+	if active && isCancel(t.Request.Request.Command) {
+		m.frames.Delete(t)
+		return pick(t, "Хорошо, отменила.", "Отменила."), nil
+	}
+
+	router := m.router.Load()
+	match := router.Match(t)
+
+	// реплика продолжает диалог, если пользователь явно не начал другую команду
+	if active && (!match.Explicit || match.Intent.Name == frame.Intent) {
+		if in, ok := router.Lookup(frame.Intent); ok {
+			return m.resume(ctx, t, in, frame)
+		}
+	}
+	if active {
+		m.frames.Delete(t)
+	}
+
+	return m.fill(ctx, t, match.Intent, match.Slots)
+}
+
+// resume записывает ответ пользователя в ожидаемый слот и продолжает диалог
+func (m *Manager) resume(ctx context.Context, t *intent.Turn, in *intent.Intent, frame Frame) (string, error) {
+	for _, slot := range in.Slots {
+		if slot.Name != frame.Awaiting {
+			continue
+		}
+
+		value := strings.TrimSpace(t.Request.Request.Command)
+		if slot.Answer != nil {
+			value = slot.Answer(t)
+		}
+		if value != "" {
+			frame.Slots[slot.Name] = value
+		}
+	}
+
+	return m.fill(ctx, t, in, frame.Slots)
+}
+
+// fill задаёт вопрос о первом недостающем обязательном слоте или, если всё собрано, вызывает обработчик интента
+func (m *Manager) fill(ctx context.Context, t *intent.Turn, in *intent.Intent, slots map[string]string) (string, error) {
+	t.Intent = in.Name
+
+	for _, slot := range in.Slots {
+		if !slot.Required || slot.Prompt == "" || slots[slot.Name] != "" {
+			continue
+		}
+
+		// без идентификатора сессии ответ на вопрос не с чем будет связать
+		if t.Request.Session.SessionID != "" {
+			m.frames.Put(t, Frame{
+				Intent:    in.Name,
+				Slots:     slots,
+				Awaiting:  slot.Name,
+				UpdatedAt: time.Now(),
+			})
+		}
+		return pick(t, slot.Prompt, slot.ShortPrompt), nil
+	}
+
+	m.frames.Delete(t)
+
+	t.Slots = slots
+	return in.Handler(ctx, t)
+}
+
+// pick выбирает вариант ответа в соответствии с предпочитаемой пользователем подробностью;
+// если краткого варианта нет, звучит подробный
+func pick(t *intent.Turn, long, short string) string {
+	if short != "" && t.Settings.Verbosity == store.VerbosityShort {
+		return short
+	}
+	return long
+}
+
+// isCancel проверяет, что пользователь просит прервать диалог
+func isCancel(command string) bool {
+	words := strings.Fields(strings.ToLower(command))
+	return len(words) > 0 && cancelWords[words[0]]
+}
+
+// SessionFrames хранит незавершённый диалог в состоянии сессии Алисы: Алиса возвращает его в следующем запросе,
+// поэтому диалог не зависит от экземпляра навыка и его перезапусков, а параллельные запросы не делят общих данных
+type SessionFrames struct {
+	ttl time.Duration
+}
+
+// NewSessionFrames создаёт хранилище, забывающее диалоги, которые не продолжались дольше ttl
+func NewSessionFrames(ttl time.Duration) *SessionFrames {
+	return &SessionFrames{ttl: ttl}
+}
+
+func (f *SessionFrames) Get(t *intent.Turn) (Frame, bool) {
+	if t.Request.State == nil || t.Request.State.Session[frameKey] == nil {
+		return Frame{}, false
+	}
+
+	// состояние сессии приходит разобранным в map, поэтому переводим его в Frame через JSON
+	var frame Frame
+	data, err := json.Marshal(t.Request.State.Session[frameKey])
+	if err != nil || json.Unmarshal(data, &frame) != nil || frame.Intent == "" {
+		return Frame{}, false
+	}
+	if time.Since(frame.UpdatedAt) > f.ttl {
+		return Frame{}, false
+	}
+	if frame.Slots == nil {
+		frame.Slots = make(map[string]string)
+	}
+	return frame, true
+}
+
+func (f *SessionFrames) Put(t *intent.Turn, frame Frame) {
+	f.update(t, &frame)
+}
+
+func (f *SessionFrames) Delete(t *intent.Turn) {
+	f.update(t, nil)
+}
+
+// update записывает в ответ состояние сессии из запроса, заменив в нём диалог на frame; nil удаляет диалог.
+// Алиса сохраняет состояние сессии из ответа целиком, поэтому чужие ключи переносятся без изменений.
+func (f *SessionFrames) update(t *intent.Turn, frame *Frame) {
+	if t.Response == nil {
+		return
+	}
+
+	state := make(map[string]any)
+	if t.Request.State != nil {
+		for k, v := range t.Request.State.Session {
+			state[k] = v
+		}
+	}
+	delete(state, frameKey)
+	if frame != nil {
+		state[frameKey] = *frame
+	}
+	t.Response.SessionState = state
+}
@@ -0,0 +1,170 @@
+package dialog
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// word возвращает слово реплики с указанным номером или пустую строку
+func word(t *intent.Turn, i int) string {
+	words := strings.Fields(t.Request.Request.Command)
+	if len(words) <= i {
+		return ""
+	}
+	return words[i]
+}
+
+func newTestManager() *Manager {
+	router := intent.NewRouter(
+		intent.Intent{Name: "fallback", Handler: func(context.Context, *intent.Turn) (string, error) {
+			return "fallback", nil
+		}},
+		intent.Intent{
+			Name:     "send",
+			Triggers: []string{"отправь"},
+			Slots: []intent.Slot{
+				{Name: "recipient", Required: true, Prompt: "Кому отправить?", ShortPrompt: "Кому?",
+					Extract: func(t *intent.Turn) string { return word(t, 1) },
+					Answer:  func(t *intent.Turn) string { return word(t, 0) }},
+				{Name: "text", Required: true, Prompt: "Что передать?",
+					Extract: func(t *intent.Turn) string { return word(t, 2) }},
+			},
+			Handler: func(_ context.Context, t *intent.Turn) (string, error) {
+				return fmt.Sprintf("%s <- %s", t.Slots["recipient"], t.Slots["text"]), nil
+			},
+		},
+		intent.Intent{Name: "done", Triggers: []string{"готово"}, Handler: func(context.Context, *intent.Turn) (string, error) {
+			return "done", nil
+		}},
+	)
+	return NewManager(router, NewSessionFrames(DefaultTTL))
+}
+
+// session ведёт диалог с менеджером так же, как Алиса: состояние сессии из ответа приходит в следующем запросе
+type session struct {
+	m        *Manager
+	state    map[string]any
+	settings store.Settings
+}
+
+func (s *session) say(t *testing.T, command string) string {
+	t.Helper()
+
+	resp := &models.Response{}
+	turn := &intent.Turn{
+		Request: &models.Request{
+			Request: models.SimpleUtterance{Command: command},
+			Session: models.Session{SessionID: "session"},
+			State:   &models.State{Session: s.state},
+		},
+		Response: resp,
+		Settings: s.settings,
+	}
+	text, err := s.m.Handle(context.Background(), turn)
+	require.NoError(t, err)
+
+	// состояние возвращается в следующем запросе в виде JSON
+	data, err := json.Marshal(resp.SessionState)
+	require.NoError(t, err)
+	s.state = nil
+	require.NoError(t, json.Unmarshal(data, &s.state))
+	return text
+}
+
+// active сообщает, что в состоянии сессии остался незавершённый диалог
+func (s *session) active() bool {
+	_, ok := s.state[frameKey]
+	return ok
+}
+
+func TestManagerFillsSlots(t *testing.T) {
+	s := &session{m: newTestManager()}
+
+	assert.Equal(t, "Кому отправить?", s.say(t, "Отправь"))
+	assert.Equal(t, "Что передать?", s.say(t, "ivan"))
+	assert.Equal(t, "ivan <- привет всем", s.say(t, "привет всем"))
+	assert.False(t, s.active())
+}
+
+func TestManagerCompleteCommand(t *testing.T) {
+	s := &session{m: newTestManager()}
+
+	assert.Equal(t, "ivan <- привет", s.say(t, "Отправь ivan привет"))
+}
+
+func TestManagerCancel(t *testing.T) {
+	s := &session{m: newTestManager()}
+
+	assert.Equal(t, "Кому отправить?", s.say(t, "Отправь"))
+	assert.Equal(t, "Хорошо, отменила.", s.say(t, "Отмена"))
+	assert.False(t, s.active())
+}
+
+func TestManagerShortReplies(t *testing.T) {
+	s := &session{m: newTestManager(), settings: store.Settings{Verbosity: store.VerbosityShort}}
+
+	assert.Equal(t, "Кому?", s.say(t, "Отправь"))
+	// у слота без краткого вопроса звучит подробный
+	assert.Equal(t, "Что передать?", s.say(t, "ivan"))
+	assert.Equal(t, "Отменила.", s.say(t, "Отмена"))
+	assert.False(t, s.active())
+}
+
+func TestManagerSwitchIntent(t *testing.T) {
+	s := &session{m: newTestManager()}
+
+	assert.Equal(t, "Кому отправить?", s.say(t, "Отправь"))
+	assert.Equal(t, "done", s.say(t, "Готово"))
+	assert.False(t, s.active())
+}
+
+func TestManagerAnswerWithTriggerInside(t *testing.T) {
+	s := &session{m: newTestManager()}
+
+	assert.Equal(t, "Что передать?", s.say(t, "Отправь ivan"))
+	assert.Equal(t, "ivan <- всё готово", s.say(t, "всё готово"))
+}
+
+func TestManagerSurvivesRestart(t *testing.T) {
+	s := &session{m: newTestManager()}
+	assert.Equal(t, "Кому отправить?", s.say(t, "Отправь"))
+
+	// следующую реплику обрабатывает другой экземпляр навыка
+	s.m = newTestManager()
+	assert.Equal(t, "Что передать?", s.say(t, "ivan"))
+	assert.Equal(t, "ivan <- привет", s.say(t, "привет"))
+}
+
+func TestSessionFrames(t *testing.T) {
+	frames := NewSessionFrames(time.Minute)
+	turn := func(state map[string]any) *intent.Turn {
+		return &intent.Turn{Request: &models.Request{State: &models.State{Session: state}}, Response: &models.Response{}}
+	}
+
+	// чужие ключи состояния сессии сохраняются
+	tr := turn(map[string]any{"other": "value"})
+	frames.Put(tr, Frame{Intent: "send", Slots: map[string]string{"recipient": "ivan"}, Awaiting: "text", UpdatedAt: time.Now()})
+	assert.Equal(t, "value", tr.Response.SessionState["other"])
+
+	frame, ok := frames.Get(turn(tr.Response.SessionState))
+	require.True(t, ok)
+	assert.Equal(t, "ivan", frame.Slots["recipient"])
+
+	frames.Delete(tr)
+	assert.Equal(t, map[string]any{"other": "value"}, tr.Response.SessionState)
+
+	// диалог, который долго не продолжали, забывается
+	frames.Put(tr, Frame{Intent: "send", UpdatedAt: time.Now().Add(-2 * time.Minute)})
+	_, ok = frames.Get(turn(tr.Response.SessionState))
+	assert.False(t, ok)
+}
@@ -0,0 +1,414 @@
+// Package grammar загружает фразы интентов из внешнего файла грамматики.
+// Файл описывает для каждого интента список фраз, в которых слоты записываются в фигурных скобках:
+//
+//	intents:
+//	  send:
+//	    - отправь {recipient:name} {text:any}
+//	    - передай {recipient:name} {text:any}
+//
+// Грамматика не содержит обработчиков: она дополняет интенты, описанные в коде, фразами-триггерами
+// и извлечением слотов, поэтому новые синонимы добавляются без пересборки навыка.
+package grammar
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"alice-skill/internal/nlu"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SlotType определяет, какие слова реплики может занять слот
+type SlotType string
+
+const (
+	SlotName   SlotType = "name"   // имя человека: сущность YANDEX.FIO или одно слово
+	SlotNumber SlotType = "number" // число: сущность YANDEX.NUMBER или слово из цифр
+	SlotAny    SlotType = "any"    // произвольный текст из одного или нескольких слов
+)
+
+// Grammar содержит скомпилированные фразы интентов
+type Grammar struct {
+	intents map[string][]phrase
+}
+
+// phrase — скомпилированная фраза: последовательность слов и слотов
+type phrase struct {
+	source string
+	parts  []part
+}
+
+// part — слово фразы либо слот; у слова пустое имя слота
+type part struct {
+	word string
+	slot string
+	typ  SlotType
+}
+
+// file описывает формат файла грамматики
+type file struct {
+	Intents map[string][]string `yaml:"intents"`
+}
+
+// Load читает и компилирует файл грамматики
+func Load(path string) (*Grammar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read grammar file: %w", err)
+	}
+
+	g, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("grammar %s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Parse компилирует грамматику из YAML. Ошибка перечисляет все найденные в грамматике проблемы.
+func Parse(data []byte) (*Grammar, error) {
+	var f file
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("cannot decode grammar: %w", err)
+	}
+	if len(f.Intents) == 0 {
+		return nil, errors.New("grammar has no intents")
+	}
+
+	g := &Grammar{intents: make(map[string][]phrase, len(f.Intents))}
+
+	var errs []error
+	for _, name := range sortedKeys(f.Intents) {
+		if len(f.Intents[name]) == 0 {
+			errs = append(errs, fmt.Errorf("intent %q: no phrases", name))
+			continue
+		}
+		for i, source := range f.Intents[name] {
+			p, err := compile(source)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("intent %q, phrase %d %q: %w", name, i+1, source, err))
+				continue
+			}
+			g.intents[name] = append(g.intents[name], p)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return g, nil
+}
+
+// compile разбирает фразу вида «отправь {recipient:name} {text:any}»
+func compile(source string) (phrase, error) {
+	p := phrase{source: source}
+	seen := make(map[string]bool)
+
+	for _, field := range strings.Fields(strings.ToLower(source)) {
+		if !strings.HasPrefix(field, "{") && !strings.HasSuffix(field, "}") {
+			if strings.ContainsAny(field, "{}") {
+				return phrase{}, fmt.Errorf("unbalanced braces in %q", field)
+			}
+			p.parts = append(p.parts, part{word: field})
+			continue
+		}
+
+		if !strings.HasPrefix(field, "{") || !strings.HasSuffix(field, "}") {
+			return phrase{}, fmt.Errorf("unbalanced braces in %q", field)
+		}
+		name, typ, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(field, "{"), "}"), ":")
+		if !ok || name == "" {
+			return phrase{}, fmt.Errorf("slot %q must look like {name:type}", field)
+		}
+		switch SlotType(typ) {
+		case SlotName, SlotNumber, SlotAny:
+		default:
+			return phrase{}, fmt.Errorf("slot %q has unknown type %q, want one of name, number, any", name, typ)
+		}
+		if seen[name] {
+			return phrase{}, fmt.Errorf("slot %q is used twice", name)
+		}
+		seen[name] = true
+
+		// два произвольных текста подряд нельзя разделить однозначно
+		if n := len(p.parts); n > 0 && p.parts[n-1].typ == SlotAny && SlotType(typ) == SlotAny {
+			return phrase{}, fmt.Errorf("slot %q of type any follows another slot of type any", name)
+		}
+		p.parts = append(p.parts, part{slot: name, typ: SlotType(typ)})
+	}
+
+	switch {
+	case len(p.parts) == 0:
+		return phrase{}, errors.New("phrase is empty")
+	case p.parts[0].slot != "":
+		return phrase{}, errors.New("phrase must start with a word")
+	}
+	return p, nil
+}
+
+// Bind дополняет интенты фразами грамматики: фразы становятся триггерами, а слоты, которые в них упомянуты,
+// сначала извлекаются по фразе, а затем, если по фразе значение не нашлось, собственным Extract слота.
+// Интенты, которых нет в грамматике, возвращаются без изменений.
+func (g *Grammar) Bind(intents []intent.Intent) ([]intent.Intent, error) {
+	known := make(map[string]bool, len(intents))
+	for _, in := range intents {
+		known[in.Name] = true
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(g.intents) {
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("intent %q is not implemented by the skill", name))
+		}
+	}
+
+	bound := make([]intent.Intent, 0, len(intents))
+	for _, in := range intents {
+		phrases, ok := g.intents[in.Name]
+		if !ok {
+			bound = append(bound, in)
+			continue
+		}
+
+		slots := make(map[string]bool, len(in.Slots))
+		for _, slot := range in.Slots {
+			slots[slot.Name] = true
+		}
+		for _, p := range phrases {
+			for _, pt := range p.parts {
+				if pt.slot != "" && !slots[pt.slot] {
+					errs = append(errs, fmt.Errorf("intent %q, phrase %q: intent has no slot %q", in.Name, p.source, pt.slot))
+				}
+			}
+		}
+
+		in.Triggers = triggers(phrases)
+		in.Slots = bindSlots(in.Slots, phrases)
+		bound = append(bound, in)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return bound, nil
+}
+
+// triggers возвращает начальные слова фраз, по которым роутер узнаёт интент
+func triggers(phrases []phrase) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, p := range phrases {
+		var words []string
+		for _, pt := range p.parts {
+			if pt.slot != "" {
+				break
+			}
+			words = append(words, pt.word)
+		}
+		trigger := strings.Join(words, " ")
+		if !seen[trigger] {
+			seen[trigger] = true
+			out = append(out, trigger)
+		}
+	}
+	return out
+}
+
+// bindSlots оборачивает извлечение слотов так, чтобы сначала использовались фразы грамматики
+func bindSlots(slots []intent.Slot, phrases []phrase) []intent.Slot {
+	bound := make([]intent.Slot, len(slots))
+	for i, slot := range slots {
+		extract, answer := slot.Extract, slot.Answer
+
+		slot.Extract = func(t *intent.Turn) string {
+			if span, ok := capture(phrases, t.Request.Request)[slot.Name]; ok {
+				// фрагмент реплики разбираем так же, как ответ на уточняющий вопрос
+				ft := fragmentTurn(t, span)
+				value := ft.Request.Request.Command
+				if answer != nil {
+					value = answer(ft)
+				}
+				if value != "" {
+					return value
+				}
+			}
+			if extract != nil {
+				return extract(t)
+			}
+			return ""
+		}
+		bound[i] = slot
+	}
+	return bound
+}
+
+// capture находит фразу, лучше всего совпавшую с репликой, и возвращает положение её слотов
+func capture(phrases []phrase, u models.SimpleUtterance) map[string]models.TokenSpan {
+	tokens := nlu.Tokens(u)
+
+	var best *result
+	for _, p := range phrases {
+		// фраза может начинаться не с первого слова: «ну ладно, отправь ...»
+		for start := 0; start < len(tokens); start++ {
+			if tokens[start] != p.parts[0].word {
+				continue
+			}
+			if r, ok := match(p.parts, tokens, start, u); ok && r.better(best) {
+				best = &r
+			}
+			break
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.spans
+}
+
+// result описывает одно совпадение фразы с репликой
+type result struct {
+	complete bool                        // совпали все части фразы
+	words    int                         // число совпавших слов фразы
+	consumed int                         // число занятых токенов реплики
+	spans    map[string]models.TokenSpan // положение слотов в реплике
+}
+
+func (r result) better(other *result) bool {
+	switch {
+	case other == nil:
+		return true
+	case r.complete != other.complete:
+		return r.complete
+	case r.words != other.words:
+		return r.words > other.words
+	default:
+		return r.consumed > other.consumed
+	}
+}
+
+// match сопоставляет части фразы с токенами, начиная с позиции pos, перебирая длины произвольного текста.
+// Если реплика закончилась раньше фразы, совпадение неполное: оставшиеся слоты навык уточнит вопросом.
+func match(parts []part, tokens []string, pos int, u models.SimpleUtterance) (result, bool) {
+	if len(parts) == 0 {
+		return result{complete: true, consumed: pos, spans: map[string]models.TokenSpan{}}, true
+	}
+	if pos >= len(tokens) {
+		// слова фразы обязательны, а слоты можно назвать позже
+		if parts[0].slot == "" {
+			return result{}, false
+		}
+		return result{consumed: pos, spans: map[string]models.TokenSpan{}}, true
+	}
+
+	pt := parts[0]
+	if pt.slot == "" {
+		if tokens[pos] != pt.word {
+			return result{}, false
+		}
+		r, ok := match(parts[1:], tokens, pos+1, u)
+		r.words++
+		return r, ok
+	}
+
+	var best *result
+	for _, end := range slotEnds(pt.typ, tokens, pos, u) {
+		r, ok := match(parts[1:], tokens, end, u)
+		if !ok {
+			continue
+		}
+		r.spans[pt.slot] = models.TokenSpan{Start: pos, End: end}
+		if r.better(best) {
+			best = &r
+		}
+	}
+	if best == nil {
+		return result{}, false
+	}
+	return *best, true
+}
+
+// slotEnds возвращает возможные границы слота, начинающегося с позиции pos
+func slotEnds(typ SlotType, tokens []string, pos int, u models.SimpleUtterance) []int {
+	switch typ {
+	case SlotName:
+		var fio bool
+		for _, e := range u.NLU.Entities {
+			if e.Type != models.EntityFIO {
+				continue
+			}
+			if e.Tokens.Start == pos && e.Tokens.End > pos {
+				return []int{e.Tokens.End}
+			}
+			fio = true
+		}
+		// если Алиса нашла имя в другом месте реплики, это слово — не имя
+		if fio {
+			return nil
+		}
+		return []int{pos + 1}
+
+	case SlotNumber:
+		for _, e := range u.NLU.Entities {
+			if e.Type == models.EntityNumber && e.Tokens.Start == pos && e.Tokens.End > pos {
+				return []int{e.Tokens.End}
+			}
+		}
+		if _, err := strconv.Atoi(tokens[pos]); err == nil {
+			return []int{pos + 1}
+		}
+		return nil
+
+	default:
+		ends := make([]int, 0, len(tokens)-pos)
+		for end := len(tokens); end > pos; end-- {
+			ends = append(ends, end)
+		}
+		return ends
+	}
+}
+
+// fragmentTurn возвращает копию хода, в которой реплика сокращена до фрагмента span вместе с сущностями Алисы
+func fragmentTurn(t *intent.Turn, span models.TokenSpan) *intent.Turn {
+	u := t.Request.Request
+	tokens := nlu.Tokens(u)[span.Start:span.End]
+
+	fragment := models.SimpleUtterance{
+		Type:              u.Type,
+		Command:           strings.Join(tokens, " "),
+		OriginalUtterance: strings.Join(tokens, " "),
+		NLU:               models.NLU{Tokens: tokens},
+	}
+	for _, e := range u.NLU.Entities {
+		if e.Tokens.Start < span.Start || e.Tokens.End > span.End {
+			continue
+		}
+		e.Tokens.Start -= span.Start
+		e.Tokens.End -= span.Start
+		fragment.NLU.Entities = append(fragment.NLU.Entities, e)
+	}
+
+	req := *t.Request
+	req.Request = fragment
+
+	ft := *t
+	ft.Request = &req
+	return &ft
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+package grammar
+
+import (
+	"alice-skill/internal/intent"
+	"alice-skill/internal/models"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGrammar = `
+intents:
+  send:
+    - отправь {recipient:name} {text:any}
+    - отправь сообщение {recipient:name} {text:any}
+    - передай {text:any} для {recipient:name}
+  read:
+    - прочитай {index:number}
+`
+
+func newTestRouter(t *testing.T, data string) *intent.Router {
+	t.Helper()
+
+	g, err := Parse([]byte(data))
+	require.NoError(t, err)
+
+	slot := func(name string) intent.Slot {
+		return intent.Slot{Name: name, Required: true}
+	}
+	intents, err := g.Bind([]intent.Intent{
+		{Name: "send", Slots: []intent.Slot{slot("recipient"), slot("text")}},
+		{Name: "read", Slots: []intent.Slot{slot("index")}},
+	})
+	require.NoError(t, err)
+
+	return intent.NewRouter(intent.Intent{Name: "fallback"}, intents...)
+}
+
+func TestGrammarMatch(t *testing.T) {
+	r := newTestRouter(t, testGrammar)
+
+	fio := func(start, end int, first string) models.Entity {
+		value, _ := json.Marshal(models.FIO{FirstName: first})
+		return models.Entity{Type: models.EntityFIO, Tokens: models.TokenSpan{Start: start, End: end}, Value: value}
+	}
+
+	testCases := []struct {
+		name        string
+		utterance   models.SimpleUtterance
+		wantIntent  string
+		wantSlots   map[string]string
+		wantMissing []string
+	}{
+		{
+			name:       "slots",
+			utterance:  models.SimpleUtterance{Command: "отправь ivan привет как дела"},
+			wantIntent: "send",
+			wantSlots:  map[string]string{"recipient": "ivan", "text": "привет как дела"},
+		},
+		{
+			name:       "longer_phrase_wins",
+			utterance:  models.SimpleUtterance{Command: "отправь сообщение ivan привет"},
+			wantIntent: "send",
+			wantSlots:  map[string]string{"recipient": "ivan", "text": "привет"},
+		},
+		{
+			name: "fio_entity",
+			utterance: models.SimpleUtterance{
+				Command: "передай привет для ивана петрова",
+				NLU: models.NLU{
+					Tokens:   []string{"передай", "привет", "для", "ивана", "петрова"},
+					Entities: []models.Entity{fio(3, 5, "иван")},
+				},
+			},
+			wantIntent: "send",
+			wantSlots:  map[string]string{"recipient": "ивана петрова", "text": "привет"},
+		},
+		{
+			name:        "missing_slot",
+			utterance:   models.SimpleUtterance{Command: "отправь ivan"},
+			wantIntent:  "send",
+			wantSlots:   map[string]string{"recipient": "ivan"},
+			wantMissing: []string{"text"},
+		},
+		{
+			name:       "number",
+			utterance:  models.SimpleUtterance{Command: "прочитай 3"},
+			wantIntent: "read",
+			wantSlots:  map[string]string{"index": "3"},
+		},
+		{
+			name:       "unknown",
+			utterance:  models.SimpleUtterance{Command: "спой песню"},
+			wantIntent: "fallback",
+			wantSlots:  map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := r.Match(&intent.Turn{Request: &models.Request{Request: tc.utterance}})
+			assert.Equal(t, tc.wantIntent, m.Intent.Name)
+			assert.Equal(t, tc.wantSlots, m.Slots)
+			assert.Equal(t, tc.wantMissing, m.Missing)
+		})
+	}
+}
+
+func TestGrammarSlotFallback(t *testing.T) {
+	g, err := Parse([]byte("intents:\n  send:\n    - отправь {recipient:name}\n"))
+	require.NoError(t, err)
+
+	intents, err := g.Bind([]intent.Intent{{
+		Name: "send",
+		Slots: []intent.Slot{{
+			Name: "recipient",
+			// ответ «меня» не является именем, поэтому значение возьмёт Extract
+			Answer:  func(t *intent.Turn) string { return "" },
+			Extract: func(t *intent.Turn) string { return "extracted" },
+		}},
+		Handler: func(_ context.Context, t *intent.Turn) (string, error) {
+			return t.Slots["recipient"], nil
+		},
+	}})
+	require.NoError(t, err)
+
+	text, err := intent.NewRouter(intent.Intent{Name: "fallback"}, intents...).Handle(context.Background(),
+		&intent.Turn{Request: &models.Request{Request: models.SimpleUtterance{Command: "отправь меня"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "extracted", text)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse([]byte(`
+intents:
+  send:
+    - "{recipient:name} привет"
+    - отправь {recipient:nam}
+    - отправь {text:any} {more:any}
+    - отправь {recipient}
+  read: []
+`))
+	require.Error(t, err)
+
+	// ошибка перечисляет все проблемы сразу, чтобы их можно было исправить за один раз
+	for _, want := range []string{
+		`intent "read": no phrases`,
+		`phrase 1 "{recipient:name} привет": phrase must start with a word`,
+		`phrase 2 "отправь {recipient:nam}": slot "recipient" has unknown type "nam"`,
+		`phrase 3 "отправь {text:any} {more:any}": slot "more" of type any follows another slot of type any`,
+		`phrase 4 "отправь {recipient}": slot "{recipient}" must look like {name:type}`,
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+
+	_, err = Parse([]byte("intent:\n  send: [отправь]\n"))
+	assert.ErrorContains(t, err, "field intent not found")
+}
+
+func TestBindErrors(t *testing.T) {
+	g, err := Parse([]byte(`intents: {send: ["отправь {who:name}"], sing: [спой]}`))
+	require.NoError(t, err)
+
+	_, err = g.Bind([]intent.Intent{{Name: "send", Slots: []intent.Slot{{Name: "recipient"}}}})
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `intent "sing" is not implemented by the skill`)
+	assert.ErrorContains(t, err, `intent "send", phrase "отправь {who:name}": intent has no slot "who"`)
+}
@@ -0,0 +1,176 @@
+// Package intent сопоставляет реплики пользователя с намерениями навыка и вызывает их обработчики.
+// Роутер не зависит от HTTP, поэтому интенты можно тестировать, передавая ему готовую модель запроса.
+package intent
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"context"
+	"strings"
+	"time"
+)
+
+// веса разных видов совпадений; совпадение более сильного вида всегда важнее длины фразы
+const (
+	weightNLU    = 3 // интент распознан грамматикой Алисы
+	weightPrefix = 2 // команда начинается с фразы-триггера
+	weightInside = 1 // фраза-триггер встречается внутри команды
+)
+
+// Turn описывает одну реплику диалога, которую обрабатывает интент
+type Turn struct {
+	Request  *models.Request   // исходный запрос Алисы
+	Response *models.Response  // ответ, в который обработчик может добавить состояния
+	Settings store.Settings    // настройки пользователя
+	Location *time.Location    // часовой пояс пользователя
+	ClientIP string            // IP-адрес, с которого пришёл запрос
+	Intent   string            // имя выбранного интента
+	Slots    map[string]string // значения слотов, извлечённые из реплики
+}
+
+// Handler обрабатывает реплику и возвращает текст ответа
+type Handler func(ctx context.Context, t *Turn) (string, error)
+
+// Slot описывает параметр интента
+type Slot struct {
+	Name        string               // имя слота
+	Required    bool                 // без этого слота интент не может быть выполнен
+	Prompt      string               // вопрос, которым навык уточняет недостающее значение
+	ShortPrompt string               // вопрос для тех, кто предпочитает краткие ответы; пустая строка — Prompt
+	Extract     func(t *Turn) string // извлекает значение слота из реплики, пустая строка — значения нет
+	Answer      func(t *Turn) string // извлекает значение из ответа на Prompt; nil — ответом считается вся реплика
+}
+
+// Intent описывает намерение пользователя
+type Intent struct {
+	Name     string   // уникальное имя, совпадает с именем интента в грамматике Алисы
+	Triggers []string // фразы, которыми пользователь выражает намерение, включая синонимы
+	Slots    []Slot   // параметры намерения
+	Handler  Handler  // обработчик реплики
+}
+
+// Match описывает результат сопоставления реплики с интентом
+type Match struct {
+	Intent   *Intent           // выбранный интент
+	Score    int               // оценка совпадения, 0 — интент выбран по умолчанию
+	Explicit bool              // команда начинается с фразы-триггера или интент распознан Алисой
+	Slots    map[string]string // найденные значения слотов
+	Missing  []string          // обязательные слоты, которых нет в реплике
+}
+
+// Router выбирает для реплики наиболее подходящий интент
+type Router struct {
+	intents  []Intent
+	fallback Intent
+}
+
+// NewRouter создаёт роутер; fallback обрабатывает реплики, для которых не нашлось ни одного интента
+func NewRouter(fallback Intent, intents ...Intent) *Router {
+	return &Router{
+		intents:  intents,
+		fallback: fallback,
+	}
+}
+
+// Match находит для реплики хода t интент с наибольшей оценкой; при равенстве выигрывает зарегистрированный раньше
+func (r *Router) Match(t *Turn) Match {
+	u := t.Request.Request
+	tokens := strings.Fields(strings.ToLower(u.Command))
+
+	best, bestScore := &r.fallback, 0
+	for i := range r.intents {
+		if s := score(&r.intents[i], u, tokens); s > bestScore {
+			best, bestScore = &r.intents[i], s
+		}
+	}
+
+	m := Match{
+		Intent:   best,
+		Score:    bestScore,
+		Explicit: bestScore >= weightPrefix*(len(tokens)+1),
+		Slots:    make(map[string]string, len(best.Slots)),
+	}
+	for _, slot := range best.Slots {
+		value := ""
+		if slot.Extract != nil {
+			value = slot.Extract(t)
+		}
+		if value != "" {
+			m.Slots[slot.Name] = value
+		} else if slot.Required {
+			m.Missing = append(m.Missing, slot.Name)
+		}
+	}
+	return m
+}
+
+// Lookup возвращает интент по имени
+func (r *Router) Lookup(name string) (*Intent, bool) {
+	for i := range r.intents {
+		if r.intents[i].Name == name {
+			return &r.intents[i], true
+		}
+	}
+	if r.fallback.Name == name {
+		return &r.fallback, true
+	}
+	return nil, false
+}
+
+// Handle выбирает интент для запроса хода t и вызывает его обработчик
+func (r *Router) Handle(ctx context.Context, t *Turn) (string, error) {
+	m := r.Match(t)
+	t.Intent = m.Intent.Name
+	t.Slots = m.Slots
+	return m.Intent.Handler(ctx, t)
+}
+
+// score оценивает, насколько реплика соответствует интенту
+func score(in *Intent, u models.SimpleUtterance, tokens []string) int {
+	// длина фразы не превышает число токенов, поэтому вес, умноженный на len(tokens)+1, определяет порядок
+	base := len(tokens) + 1
+
+	if _, ok := u.NLU.Intents[in.Name]; ok {
+		return weightNLU * base
+	}
+
+	best := 0
+	for _, trigger := range in.Triggers {
+		phrase := strings.Fields(strings.ToLower(trigger))
+		pos := indexOf(tokens, phrase)
+		if pos < 0 {
+			continue
+		}
+
+		// длинная фраза точнее короткой: «не называй время» важнее «называй время»
+		weight := weightInside
+		if pos == 0 {
+			weight = weightPrefix
+		}
+		s := weight*base + len(phrase)
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// indexOf возвращает позицию первого вхождения фразы в список токенов или -1
+func indexOf(tokens, phrase []string) int {
+	if len(phrase) == 0 {
+		return -1
+	}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		match := true
+		for j := range phrase {
+			if tokens[i+j] != phrase[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
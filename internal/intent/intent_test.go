@@ -0,0 +1,117 @@
+package intent
+
+import (
+	"alice-skill/internal/models"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reply возвращает обработчик, который отвечает именем интента
+func reply(name string) Handler {
+	return func(_ context.Context, t *Turn) (string, error) {
+		return name + ":" + t.Slots["text"], nil
+	}
+}
+
+func newTestRouter() *Router {
+	return NewRouter(
+		Intent{Name: "fallback", Handler: reply("fallback")},
+		Intent{
+			Name:     "send",
+			Triggers: []string{"отправь", "напиши", "передай"},
+			Slots: []Slot{
+				{Name: "text", Required: true, Extract: func(t *Turn) string {
+					words := strings.Fields(t.Request.Request.Command)
+					if len(words) < 2 {
+						return ""
+					}
+					return strings.Join(words[1:], " ")
+				}},
+			},
+			Handler: reply("send"),
+		},
+		Intent{Name: "time_on", Triggers: []string{"называй время"}, Handler: reply("time_on")},
+		Intent{Name: "time_off", Triggers: []string{"не называй время"}, Handler: reply("time_off")},
+		Intent{Name: "done", Triggers: []string{"готово"}, Handler: reply("done")},
+	)
+}
+
+func TestRouterMatch(t *testing.T) {
+	r := newTestRouter()
+
+	testCases := []struct {
+		name        string
+		utterance   models.SimpleUtterance
+		wantIntent  string
+		wantMissing []string
+	}{
+		{
+			name:       "trigger",
+			utterance:  models.SimpleUtterance{Command: "Отправь ivan привет"},
+			wantIntent: "send",
+		},
+		{
+			name:       "synonym",
+			utterance:  models.SimpleUtterance{Command: "передай ivan привет"},
+			wantIntent: "send",
+		},
+		{
+			name:        "missing_slot",
+			utterance:   models.SimpleUtterance{Command: "напиши"},
+			wantIntent:  "send",
+			wantMissing: []string{"text"},
+		},
+		{
+			name:       "longer_phrase_wins",
+			utterance:  models.SimpleUtterance{Command: "не называй время при входе"},
+			wantIntent: "time_off",
+		},
+		{
+			name:       "prefix_beats_inside",
+			utterance:  models.SimpleUtterance{Command: "напиши ivan готово"},
+			wantIntent: "send",
+		},
+		{
+			name:       "inside",
+			utterance:  models.SimpleUtterance{Command: "всё готово"},
+			wantIntent: "done",
+		},
+		{
+			name: "alice_nlu_intent",
+			utterance: models.SimpleUtterance{
+				Command: "отправь что-нибудь",
+				NLU:     models.NLU{Intents: map[string]models.Intent{"done": {}}},
+			},
+			wantIntent: "done",
+		},
+		{
+			name:       "fallback",
+			utterance:  models.SimpleUtterance{Command: "sudo do something"},
+			wantIntent: "fallback",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := r.Match(&Turn{Request: &models.Request{Request: tc.utterance}})
+			assert.Equal(t, tc.wantIntent, m.Intent.Name)
+			assert.Equal(t, tc.wantMissing, m.Missing)
+		})
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	r := newTestRouter()
+
+	turn := &Turn{Request: &models.Request{Request: models.SimpleUtterance{Command: "Напиши ivan привет"}}}
+	text, err := r.Handle(context.Background(), turn)
+	require.NoError(t, err)
+
+	assert.Equal(t, "send:ivan привет", text)
+	assert.Equal(t, "send", turn.Intent)
+	assert.Equal(t, map[string]string{"text": "ivan привет"}, turn.Slots)
+}
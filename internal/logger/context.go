@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type loggerKey struct{}
+
+// максимальная длина идентификатора запроса, который принимается от клиента
+const maxRequestIDLength = 64
+
+// WithLogger возвращает контекст, в котором хранится логер l
+func WithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext возвращает логер запроса, а если его нет в контексте — синглтон Log
+func FromContext(ctx context.Context) *zap.Logger {
+	return FromContextOr(ctx, Log)
+}
+
+// FromContextOr возвращает логер запроса, а если его нет в контексте — fallback.
+// Так компоненты с собственным логером пишут события запроса вместе с его идентификаторами.
+func FromContextOr(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// With возвращает контекст с логером, дополненным полями fields
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(fields...))
+}
+
+// RequestID — middleware, назначающий запросу идентификатор. Идентификатор из заголовка X-Request-ID
+// сохраняется, если он достаточно короткий и не содержит посторонних символов; иначе создаётся новый.
+// Идентификатор возвращается клиенту в том же заголовке и добавляется ко всем строкам логера запроса.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := With(r.Context(), zap.String("request_id", id))
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand не возвращает ошибок на поддерживаемых платформах
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRequestID(t *testing.T) {
+	logs := observe(t)
+
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get(RequestIDHeader)
+		FromContext(r.Context()).Info("handled")
+	}))
+
+	testCases := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "incoming", incoming: "abc-123_x.y", keep: true},
+		{name: "missing"},
+		{name: "too_long", incoming: string(make([]byte, maxRequestIDLength+1))},
+		{name: "log_injection", incoming: "id\nfake log line"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.incoming != "" {
+				r.Header.Set(RequestIDHeader, tc.incoming)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if tc.keep {
+				assert.Equal(t, tc.incoming, seen)
+			} else {
+				assert.NotEqual(t, tc.incoming, seen)
+				assert.Len(t, seen, 32)
+			}
+			assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+
+			entries := logs.TakeAll()
+			require.Len(t, entries, 1)
+			assert.Equal(t, seen, entries[0].ContextMap()["request_id"])
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	logs := observe(t)
+
+	// без логера в контексте используется синглтон
+	assert.Same(t, Log, FromContext(context.Background()))
+
+	ctx := With(context.Background(), zap.String("session_id", "s1"))
+	ctx = With(ctx, zap.String("user", "u1"))
+	FromContext(ctx).Info("hello")
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]any{"session_id": "s1", "user": "u1"}, logs.All()[0].ContextMap())
+}
@@ -1,9 +1,16 @@
 package logger
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Log будет доступен всему коду как синглтон.
@@ -11,10 +18,17 @@ import (
 // По умолчанию установлен no-op-логер, который не выводит никаких сообщений.
 var Log *zap.Logger = zap.NewNop()
 
+// Level — текущий уровень логирования синглтона Log. Его можно менять во время работы, не пересоздавая логер;
+// AtomicLevel умеет отдавать и менять уровень по HTTP в формате {"level":"debug"}.
+var Level = zap.NewAtomicLevel()
+
+// уровень, заданный при инициализации; к нему возвращают ResetLevel и ToggleDebug
+var configuredLevel atomic.Int32
+
 // Initialize инициализирует синглтон логера с необходимым уровнем логирования.
 func Initialize(level string) error {
-	// преобразуем текстовый уровень логирования в zap.AtomicLevel
-	lvl, err := zap.ParseAtomicLevel(level)
+	// преобразуем текстовый уровень логирования в zapcore.Level
+	lvl, err := zapcore.ParseLevel(level)
 	if err != nil {
 		return err
 	}
@@ -22,8 +36,10 @@ func Initialize(level string) error {
 	// создаём новую конфигурацию логгера
 	cfg := zap.NewProductionConfig()
 
-	// устанавливаем уровень
-	cfg.Level = lvl
+	// устанавливаем уровень; логер сохраняет ссылку на Level, поэтому его изменения действуют сразу
+	Level.SetLevel(lvl)
+	configuredLevel.Store(int32(lvl))
+	cfg.Level = Level
 
 	// создаём логгер на основе конфигурации
 	zl, err := cfg.Build()
@@ -37,13 +53,115 @@ func Initialize(level string) error {
 	return nil
 }
 
+// ResetLevel возвращает уровень, заданный при инициализации
+func ResetLevel() zapcore.Level {
+	lvl := zapcore.Level(configuredLevel.Load())
+	Level.SetLevel(lvl)
+	return lvl
+}
+
+// ToggleDebug включает уровень debug, а если он уже включён — возвращает уровень, заданный при инициализации.
+// Возвращает установленный уровень.
+func ToggleDebug() zapcore.Level {
+	if Level.Level() == zapcore.DebugLevel {
+		return ResetLevel()
+	}
+	Level.SetLevel(zapcore.DebugLevel)
+	return zapcore.DebugLevel
+}
+
 // RequestLogger — middleware-логер для входящих HTTP-запросов.
+// После обработки запроса пишет в журнал уровня info строку доступа со статусом, размером ответа и временем обработки.
 func RequestLogger(h http.HandlerFunc) http.Handler {
+	return SampledRequestLogger(1, h)
+}
+
+// SampledRequestLogger работает как RequestLogger, но для часто вызываемых маршрутов пишет только каждый every-й
+// успешный запрос. Запросы, завершившиеся ошибкой, пишутся всегда.
+func SampledRequestLogger(every uint64, h http.HandlerFunc) http.Handler {
+	var counter atomic.Uint64
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		Log.Debug("got incoming HTTP request",
+		start := time.Now()
+
+		// обработчики дополняют запись подробностями запроса Алисы через Annotate
+		rec := &accessRecord{}
+		r = r.WithContext(context.WithValue(r.Context(), accessKey{}, rec))
+
+		lw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		h(lw, r)
+
+		if lw.status < http.StatusInternalServerError && every > 1 && counter.Add(1)%every != 1 {
+			return
+		}
+
+		// идентификатор запроса уже есть в логере запроса, если перед журналом стоит middleware RequestID
+		fields := []zap.Field{
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
-		)
-		h(w, r)
+			zap.Int("status", lw.status),
+			zap.Int("size", lw.size),
+			zap.Duration("duration", time.Since(start)),
+		}
+		FromContext(r.Context()).Info("HTTP request served", append(fields, rec.get()...)...)
 	})
 }
+
+// RequestIDHeader — заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+type accessKey struct{}
+
+// accessRecord накапливает поля записи журнала доступа; обработчик может дополнять её из других горутин
+type accessRecord struct {
+	mu     sync.Mutex
+	fields []zap.Field
+}
+
+func (r *accessRecord) add(fields ...zap.Field) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fields = append(r.fields, fields...)
+}
+
+func (r *accessRecord) get() []zap.Field {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]zap.Field(nil), r.fields...)
+}
+
+// Annotate добавляет поля в строку журнала доступа текущего запроса.
+// Вне RequestLogger ничего не делает.
+func Annotate(ctx context.Context, fields ...zap.Field) {
+	if rec, ok := ctx.Value(accessKey{}).(*accessRecord); ok {
+		rec.add(fields...)
+	}
+}
+
+// HashUserID возвращает необратимый короткий отпечаток идентификатора пользователя:
+// по нему можно связать записи одного пользователя, не храня сам идентификатор в журнале
+func HashUserID(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// loggingResponseWriter запоминает статус и размер ответа
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *loggingResponseWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *loggingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observe подменяет синглтон логера наблюдателем на время теста
+func observe(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.InfoLevel)
+	prev := Log
+	Log = zap.New(core)
+	t.Cleanup(func() { Log = prev })
+	return logs
+}
+
+func TestRequestLogger(t *testing.T) {
+	logs := observe(t)
+
+	h := RequestID(RequestLogger(func(w http.ResponseWriter, r *http.Request) {
+		Annotate(r.Context(), zap.String("intent", "send"), zap.String("user", HashUserID("user1")))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/alice/webhook", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "/alice/webhook", fields["path"])
+	assert.EqualValues(t, http.StatusCreated, fields["status"])
+	assert.EqualValues(t, 5, fields["size"])
+	assert.Equal(t, "req-1", fields["request_id"])
+	assert.Equal(t, "send", fields["intent"])
+	assert.Contains(t, fields, "duration")
+
+	// в журнал попадает отпечаток, а не сам идентификатор пользователя
+	assert.NotEqual(t, "user1", fields["user"])
+	assert.Len(t, fields["user"], 16)
+}
+
+func TestSampledRequestLogger(t *testing.T) {
+	logs := observe(t)
+
+	status := http.StatusOK
+	h := SampledRequestLogger(10, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	for i := 0; i < 25; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 3, logs.Len())
+
+	// ошибки пишутся без выборки
+	status = http.StatusServiceUnavailable
+	for i := 0; i < 5; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	}
+	assert.Equal(t, 8, logs.Len())
+}
+
+func TestHashUserID(t *testing.T) {
+	assert.Equal(t, HashUserID("user1"), HashUserID("user1"))
+	assert.NotEqual(t, HashUserID("user1"), HashUserID("user2"))
+	assert.Empty(t, HashUserID(""))
+}
+
+func TestToggleDebug(t *testing.T) {
+	log := Log
+	t.Cleanup(func() {
+		Log = log
+		configuredLevel.Store(int32(zapcore.InfoLevel))
+		Level.SetLevel(zapcore.InfoLevel)
+	})
+
+	require.NoError(t, Initialize("warn"))
+	assert.False(t, Log.Core().Enabled(zapcore.DebugLevel))
+
+	// логер видит изменение уровня без пересоздания
+	assert.Equal(t, zapcore.DebugLevel, ToggleDebug())
+	assert.True(t, Log.Core().Enabled(zapcore.DebugLevel))
+
+	assert.Equal(t, zapcore.WarnLevel, ToggleDebug())
+	assert.False(t, Log.Core().Enabled(zapcore.InfoLevel))
+
+	Level.SetLevel(zapcore.ErrorLevel)
+	assert.Equal(t, zapcore.WarnLevel, ResetLevel())
+	assert.Equal(t, zapcore.WarnLevel, Level.Level())
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// интент запросов, для которых он не был определён, например отклонённых до разбора тела
+const noIntent = "none"
+
+type intentKey struct{}
+
+// intentHolder хранит имя интента; его записывает обработчик, возможно из другой горутины
+type intentHolder struct {
+	mu   sync.Mutex
+	name string
+}
+
+// SetIntent сообщает middleware Instrument, какой интент обработал запрос.
+// Вне Instrument ничего не делает.
+func SetIntent(ctx context.Context, name string) {
+	if h, ok := ctx.Value(intentKey{}).(*intentHolder); ok {
+		h.mu.Lock()
+		h.name = name
+		h.mu.Unlock()
+	}
+}
+
+// Instrument — middleware, который считает запросы к webhook и измеряет их длительность по интенту и статусу
+func Instrument(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		holder := &intentHolder{name: noIntent}
+		r = r.WithContext(context.WithValue(r.Context(), intentKey{}, holder))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+
+		holder.mu.Lock()
+		name := holder.name
+		holder.mu.Unlock()
+		if name == "" {
+			name = noIntent
+		}
+
+		Requests.WithLabelValues(name, strconv.Itoa(sw.status)).Inc()
+		RequestDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}
+}
+
+// statusWriter запоминает HTTP-статус ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
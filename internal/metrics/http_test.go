@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	testCases := []struct {
+		name       string
+		intent     string
+		status     int
+		wantIntent string
+	}{
+		{name: "intent", intent: "send", status: http.StatusOK, wantIntent: "send"},
+		{name: "rejected_before_intent", status: http.StatusForbidden, wantIntent: noIntent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := Requests.WithLabelValues(tc.wantIntent, strconv.Itoa(tc.status))
+			before := testutil.ToFloat64(requests)
+
+			h := Instrument(func(w http.ResponseWriter, r *http.Request) {
+				if tc.intent != "" {
+					SetIntent(r.Context(), tc.intent)
+				}
+				w.WriteHeader(tc.status)
+			})
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/alice/webhook", nil))
+
+			assert.Equal(t, before+1, testutil.ToFloat64(requests))
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package metrics содержит метрики навыка в формате Prometheus.
+// Метрики регистрируются в реестре по умолчанию и отдаются обработчиком promhttp.Handler.
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// пространство имён всех метрик навыка
+const namespace = "alice_skill"
+
+// причины, по которым навык отвечает пользователю упрощённым ответом
+const (
+	ReasonDeadline = "deadline" // обработка не уложилась в отведённое Алисой время
+	ReasonPanic    = "panic"    // обработка запроса завершилась паникой
+)
+
+// DegradedResponses считает ответы, в которых навык не смог выполнить команду и попросил повторить её позже
+var DegradedResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "degraded_responses_total",
+	Help:      "Number of webhook responses replaced with a fallback reply.",
+}, []string{"reason"})
+
+// Panics считает паники, перехваченные при обработке запросов к webhook
+var Panics = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "panics_total",
+	Help:      "Number of panics recovered while handling webhook requests.",
+})
+
+// RateLimited считает команды, отклонённые из-за превышения частоты
+var RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rate_limited_total",
+	Help:      "Number of commands rejected by rate limits.",
+}, []string{"intent"})
+
+// Requests считает запросы к webhook по интенту и HTTP-статусу ответа
+var Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "webhook_requests_total",
+	Help:      "Number of webhook requests by intent and HTTP status.",
+}, []string{"intent", "status"})
+
+// RequestDuration измеряет время обработки запросов к webhook по интенту
+var RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "webhook_request_duration_seconds",
+	Help:      "Webhook request latency by intent.",
+	// Алиса ждёт ответа около трёх секунд, поэтому интересны границы до этого значения
+	Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2, 2.5, 3, 5},
+}, []string{"intent"})
+
+// GzipUsage считает запросы со сжатым телом и сжатые ответы
+var GzipUsage = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "gzip_total",
+	Help:      "Number of gzip-compressed request bodies and responses.",
+}, []string{"direction"})
+
+// messageQueueDepth возвращает текущую длину канала отложенного сохранения, см. TrackMessageQueue
+var messageQueueDepth atomic.Pointer[func() int]
+
+// MessageQueueDepth показывает, сколько сообщений ждёт в канале отложенного сохранения.
+// Длина читается в момент сбора метрик, поэтому видна и растущая очередь, и опустевшая.
+var MessageQueueDepth = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "message_queue_depth",
+	Help:      "Number of messages waiting in the flush channel.",
+}, func() float64 {
+	if depth := messageQueueDepth.Load(); depth != nil {
+		return float64((*depth)())
+	}
+	return 0
+})
+
+// TrackMessageQueue задаёт функцию, по которой MessageQueueDepth узнаёт длину канала отложенного сохранения
+func TrackMessageQueue(depth func() int) {
+	messageQueueDepth.Store(&depth)
+}
+
+// PendingMessages показывает, сколько сообщений прочитано из канала, но ещё не сохранено
+var PendingMessages = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "pending_messages",
+	Help:      "Number of messages taken from the channel and not yet saved.",
+})
+
+// FlushBatchSize измеряет число сообщений, сохраняемых за один раз
+var FlushBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "flush_batch_size",
+	Help:      "Number of messages saved in one flush.",
+	Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+})
+
+// FlushFailures считает неудачные попытки сохранить накопленные сообщения
+var FlushFailures = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "flush_failures_total",
+	Help:      "Number of failed message flushes.",
+})
+
+// StoreDuration измеряет время вызовов хранилища по методу и результату
+var StoreDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "store_call_duration_seconds",
+	Help:      "Store call latency by method and result.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"method", "result"})
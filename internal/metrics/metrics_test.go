@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageQueueDepth(t *testing.T) {
+	queue := make(chan int, 4)
+	TrackMessageQueue(func() int { return len(queue) })
+
+	// значение читается при сборе метрик, без участия того, кто разбирает очередь
+	assert.Equal(t, 0.0, testutil.ToFloat64(MessageQueueDepth))
+	queue <- 1
+	queue <- 2
+	assert.Equal(t, 2.0, testutil.ToFloat64(MessageQueueDepth))
+	<-queue
+	assert.Equal(t, 1.0, testutil.ToFloat64(MessageQueueDepth))
+}
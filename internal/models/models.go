@@ -1,40 +1,265 @@
 package models
 
+import "encoding/json"
+
 const (
 	TypeSimpleUtterance = "SimpleUtterance"
+	TypeButtonPressed   = "ButtonPressed"
 )
 
-// Описывает команду, полученную в запросе типа
-type SimpleUtterance struct {
-	Type    string `json:"type"`
-	Command string `json:"command"`
-}
+// Типы именованных сущностей, которые Алиса извлекает из реплики пользователя
+// https://yandex.ru/dev/dialogs/alice/doc/nlu.html
+const (
+	EntityFIO      = "YANDEX.FIO"
+	EntityGeo      = "YANDEX.GEO"
+	EntityDateTime = "YANDEX.DATETIME"
+	EntityNumber   = "YANDEX.NUMBER"
+)
 
 // Описывает запрос пользователя
 // https://yandex.ru/dev/dialogs/alice/doc/request.html
 type Request struct {
-	Timezone string          `json:"timezone"`
-	Request  SimpleUtterance `json:"request"`
-	Session  Session         `json:"session"`
-	Version  string          `json:"version"`
+	Meta    Meta            `json:"meta"`
+	Request SimpleUtterance `json:"request"`
+	Session Session         `json:"session"`
+	State   *State          `json:"state,omitempty"`
+	Version string          `json:"version"`
+}
+
+// Описывает информацию об устройстве, с помощью которого пользователь разговаривает с Алисой
+type Meta struct {
+	Locale     string     `json:"locale"`
+	Timezone   string     `json:"timezone"`
+	ClientID   string     `json:"client_id"`
+	Interfaces Interfaces `json:"interfaces"`
+}
+
+// Описывает интерфейсы, доступные на устройстве пользователя
+type Interfaces struct {
+	Screen         *Interface `json:"screen,omitempty"`
+	Payments       *Interface `json:"payments,omitempty"`
+	AccountLinking *Interface `json:"account_linking,omitempty"`
+	AudioPlayer    *Interface `json:"audio_player,omitempty"`
+}
+
+// Interface — маркер наличия интерфейса, Алиса передаёт его пустым объектом
+type Interface struct{}
+
+// Описывает команду, полученную в запросе типа
+type SimpleUtterance struct {
+	Type              string          `json:"type"`
+	Command           string          `json:"command"`
+	OriginalUtterance string          `json:"original_utterance"`
+	Markup            *Markup         `json:"markup,omitempty"`
+	Payload           json.RawMessage `json:"payload,omitempty"`
+	NLU               NLU             `json:"nlu"`
+}
+
+// Описывает формальные характеристики реплики
+type Markup struct {
+	DangerousContext bool `json:"dangerous_context"`
+}
+
+// Описывает результат разбора реплики встроенным NLU Алисы
+type NLU struct {
+	Tokens   []string          `json:"tokens"`
+	Entities []Entity          `json:"entities"`
+	Intents  map[string]Intent `json:"intents"`
+}
+
+// Описывает именованную сущность, найденную в реплике
+type Entity struct {
+	Tokens TokenSpan       `json:"tokens"`
+	Type   string          `json:"type"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// Описывает положение сущности в списке токенов: Start включительно, End не включительно
+type TokenSpan struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Описывает интент, распознанный по грамматике навыка
+type Intent struct {
+	Slots map[string]Slot `json:"slots"`
+}
+
+// Описывает слот интента
+type Slot struct {
+	Type   string          `json:"type"`
+	Tokens *TokenSpan      `json:"tokens,omitempty"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// Описывает значение сущности YANDEX.FIO
+type FIO struct {
+	FirstName      string `json:"first_name,omitempty"`
+	PatronymicName string `json:"patronymic_name,omitempty"`
+	LastName       string `json:"last_name,omitempty"`
+}
+
+// Описывает значение сущности YANDEX.GEO
+type Geo struct {
+	Country     string `json:"country,omitempty"`
+	City        string `json:"city,omitempty"`
+	Street      string `json:"street,omitempty"`
+	HouseNumber string `json:"house_number,omitempty"`
+	Airport     string `json:"airport,omitempty"`
 }
+
+// Описывает значение сущности YANDEX.DATETIME.
+// Неуказанные пользователем части даты равны nil; признак *IsRelative означает смещение относительно текущего момента.
+type DateTime struct {
+	Year             *int `json:"year,omitempty"`
+	YearIsRelative   bool `json:"year_is_relative,omitempty"`
+	Month            *int `json:"month,omitempty"`
+	MonthIsRelative  bool `json:"month_is_relative,omitempty"`
+	Day              *int `json:"day,omitempty"`
+	DayIsRelative    bool `json:"day_is_relative,omitempty"`
+	Hour             *int `json:"hour,omitempty"`
+	HourIsRelative   bool `json:"hour_is_relative,omitempty"`
+	Minute           *int `json:"minute,omitempty"`
+	MinuteIsRelative bool `json:"minute_is_relative,omitempty"`
+}
+
+// FIO возвращает значение сущности типа YANDEX.FIO
+func (e Entity) FIO() (FIO, error) {
+	var v FIO
+	err := json.Unmarshal(e.Value, &v)
+	return v, err
+}
+
+// Geo возвращает значение сущности типа YANDEX.GEO
+func (e Entity) Geo() (Geo, error) {
+	var v Geo
+	err := json.Unmarshal(e.Value, &v)
+	return v, err
+}
+
+// DateTime возвращает значение сущности типа YANDEX.DATETIME
+func (e Entity) DateTime() (DateTime, error) {
+	var v DateTime
+	err := json.Unmarshal(e.Value, &v)
+	return v, err
+}
+
+// Number возвращает значение сущности типа YANDEX.NUMBER
+func (e Entity) Number() (float64, error) {
+	var v float64
+	err := json.Unmarshal(e.Value, &v)
+	return v, err
+}
+
+// Описывает сессию диалога
 type Session struct {
-	New  bool `json:"new"`
-	User User
+	MessageID   int         `json:"message_id"`
+	SessionID   string      `json:"session_id"`
+	SkillID     string      `json:"skill_id"`
+	UserID      string      `json:"user_id,omitempty"` // устаревшее поле, совпадает с Application.ApplicationID
+	User        User        `json:"user"`
+	Application Application `json:"application"`
+	New         bool        `json:"new"`
 }
 
+// Описывает авторизованного пользователя Яндекса
 type User struct {
-	UserID string `json:"userid"`
+	UserID      string `json:"user_id"`
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+// Описывает экземпляр приложения, через которое пользователь разговаривает с Алисой
+type Application struct {
+	ApplicationID string `json:"application_id"`
+}
+
+// Identity возвращает идентификатор собеседника: пользователя Яндекса, если он авторизован, иначе экземпляра приложения
+func (s Session) Identity() string {
+	if s.User.UserID != "" {
+		return s.User.UserID
+	}
+	if s.Application.ApplicationID != "" {
+		return s.Application.ApplicationID
+	}
+	return s.UserID
+}
+
+// Описывает сохранённые Алисой состояния навыка
+// https://yandex.ru/dev/dialogs/alice/doc/session-persistence.html
+type State struct {
+	Session     map[string]any `json:"session,omitempty"`     // состояние текущей сессии
+	User        map[string]any `json:"user,omitempty"`        // состояние авторизованного пользователя
+	Application map[string]any `json:"application,omitempty"` // состояние экземпляра приложения
 }
 
 // Описывает ответ, который нужно озвучить
 type ResponsePayload struct {
+	Text       string   `json:"text"`
+	TTS        string   `json:"tts,omitempty"`
+	Card       *Card    `json:"card,omitempty"`
+	Buttons    []Button `json:"buttons,omitempty"`
+	EndSession bool     `json:"end_session"`
+}
+
+// Описывает кнопку-подсказку под ответом
+type Button struct {
+	Title   string `json:"title"`
+	Payload any    `json:"payload,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Hide    bool   `json:"hide,omitempty"`
+}
+
+// Типы карточек в ответе
+const (
+	CardBigImage     = "BigImage"
+	CardItemsList    = "ItemsList"
+	CardImageGallery = "ImageGallery"
+)
+
+// Описывает карточку с изображениями
+type Card struct {
+	Type        string      `json:"type"`
+	ImageID     string      `json:"image_id,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Button      *CardButton `json:"button,omitempty"`
+	Header      *CardHeader `json:"header,omitempty"`
+	Items       []CardItem  `json:"items,omitempty"`
+	Footer      *CardFooter `json:"footer,omitempty"`
+}
+
+// Описывает кнопку, по которой можно нажать на карточке или её элементе
+type CardButton struct {
+	Text    string `json:"text,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Payload any    `json:"payload,omitempty"`
+}
+
+// Описывает заголовок списка ItemsList
+type CardHeader struct {
 	Text string `json:"text"`
 }
 
+// Описывает элемент списка ItemsList или галереи ImageGallery
+type CardItem struct {
+	ImageID     string      `json:"image_id,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Button      *CardButton `json:"button,omitempty"`
+}
+
+// Описывает подвал списка ItemsList
+type CardFooter struct {
+	Text   string      `json:"text"`
+	Button *CardButton `json:"button,omitempty"`
+}
+
 // Описывает ответ сервера
 // https://yandex.ru/dev/dialogs/alice/doc/response.html
 type Response struct {
-	Response ResponsePayload `json:"response"`
-	Version  string          `json:"version"`
+	Response         ResponsePayload `json:"response"`
+	SessionState     map[string]any  `json:"session_state,omitempty"`     // новое состояние сессии
+	UserStateUpdate  map[string]any  `json:"user_state_update,omitempty"` // изменения состояния пользователя, null удаляет ключ
+	ApplicationState map[string]any  `json:"application_state,omitempty"` // новое состояние приложения целиком
+	Version          string          `json:"version"`
 }
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Записанных запросов платформы у навыка нет, поэтому запросы в testdata собраны вручную
+// по описанию протокола в документации Яндекс Диалогов, раздел «Формат запроса»:
+//   - simple_utterance.json — реплика авторизованного пользователя с сущностями YANDEX.FIO,
+//     YANDEX.DATETIME и YANDEX.NUMBER, слотом интента и состояниями всех трёх видов;
+//   - new_session.json — первый запрос сессии на колонке: пустая реплика, нет экрана;
+//   - button_pressed.json — нажатие кнопки с payload, без авторизованного пользователя.
+//
+// Идентификаторы и токен доступа вымышлены, но идентификаторы имеют формат платформы: навык — UUID,
+// пользователь и приложение — 64 шестнадцатеричных символа. Формат проверяет TestFixtureIdentifiers.
+
+// decodeFixture строго разбирает запрос Алисы: любое неизвестное поле считается ошибкой модели
+func decodeFixture(t *testing.T, name string) Request {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var req Request
+	require.NoError(t, dec.Decode(&req))
+	return req
+}
+
+func TestRequestSimpleUtterance(t *testing.T) {
+	req := decodeFixture(t, "simple_utterance.json")
+
+	assert.Equal(t, "ru-RU", req.Meta.Locale)
+	assert.Equal(t, "Europe/Moscow", req.Meta.Timezone)
+	assert.NotEmpty(t, req.Meta.ClientID)
+	assert.NotNil(t, req.Meta.Interfaces.Screen)
+	assert.NotNil(t, req.Meta.Interfaces.AccountLinking)
+	assert.Nil(t, req.Meta.Interfaces.AudioPlayer)
+
+	assert.Equal(t, 3, req.Session.MessageID)
+	assert.Equal(t, "2eac4854-fce721f3-b845abba-20d60", req.Session.SessionID)
+	assert.Equal(t, "3ad36498-f5ed-4079-a14b-788652932056", req.Session.SkillID)
+	assert.Equal(t, "6C91DA5198D1758C6A9F63A7C5CDDF09359F683B13A19A151FBA4C8B092BB838", req.Session.User.UserID)
+	assert.Equal(t, "AgAAAAAB4vpbAAApoR1oaCd5yR6eiXSHqOGT8dT", req.Session.User.AccessToken)
+	assert.Equal(t, req.Session.User.UserID, req.Session.Identity())
+	assert.False(t, req.Session.New)
+
+	assert.Equal(t, TypeSimpleUtterance, req.Request.Type)
+	assert.Equal(t, "Отправь Ивану Петрову привет завтра в 10 часов", req.Request.OriginalUtterance)
+	require.NotNil(t, req.Request.Markup)
+	assert.False(t, req.Request.Markup.DangerousContext)
+	assert.Len(t, req.Request.NLU.Tokens, 8)
+
+	require.Len(t, req.Request.NLU.Entities, 3)
+
+	fio, err := req.Request.NLU.Entities[0].FIO()
+	require.NoError(t, err)
+	assert.Equal(t, EntityFIO, req.Request.NLU.Entities[0].Type)
+	assert.Equal(t, TokenSpan{Start: 1, End: 3}, req.Request.NLU.Entities[0].Tokens)
+	assert.Equal(t, FIO{FirstName: "иван", LastName: "петров"}, fio)
+
+	dt, err := req.Request.NLU.Entities[1].DateTime()
+	require.NoError(t, err)
+	require.NotNil(t, dt.Day)
+	require.NotNil(t, dt.Hour)
+	assert.Equal(t, 1, *dt.Day)
+	assert.True(t, dt.DayIsRelative)
+	assert.Equal(t, 10, *dt.Hour)
+	assert.Nil(t, dt.Minute)
+
+	n, err := req.Request.NLU.Entities[2].Number()
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), n)
+
+	slot := req.Request.NLU.Intents["send"].Slots["recipient"]
+	assert.Equal(t, EntityFIO, slot.Type)
+	assert.Equal(t, &TokenSpan{Start: 1, End: 3}, slot.Tokens)
+
+	require.NotNil(t, req.State)
+	assert.Equal(t, "confirm", req.State.Session["step"])
+	assert.Contains(t, req.State.User, "dialog")
+	assert.NotNil(t, req.State.Application)
+}
+
+func TestRequestNewSession(t *testing.T) {
+	req := decodeFixture(t, "new_session.json")
+
+	assert.True(t, req.Session.New)
+	assert.Equal(t, 0, req.Session.MessageID)
+	assert.Empty(t, req.Session.User.UserID)
+	assert.Equal(t, "AC9A21CCB2F2C2F5F7E2E2FD3C0B7E21A0E0C8C7E1C8D1F9F1A7E3B2C5D6E7F8", req.Session.Identity())
+	assert.Empty(t, req.Request.Command)
+	assert.Nil(t, req.Request.Markup)
+	assert.Empty(t, req.Request.NLU.Entities)
+	require.NotNil(t, req.State)
+	assert.Nil(t, req.State.User)
+}
+
+func TestRequestButtonPressed(t *testing.T) {
+	req := decodeFixture(t, "button_pressed.json")
+
+	assert.Equal(t, TypeButtonPressed, req.Request.Type)
+	assert.JSONEq(t, `{"action": "read", "index": 1}`, string(req.Request.Payload))
+	assert.Nil(t, req.State)
+}
+
+func TestRequestUnknownField(t *testing.T) {
+	dec := json.NewDecoder(bytes.NewBufferString(`{"session": {"userid": "legacy"}}`))
+	dec.DisallowUnknownFields()
+
+	var req Request
+	assert.Error(t, dec.Decode(&req), "legacy userid tag must not be accepted")
+}
+
+func TestResponseEncoding(t *testing.T) {
+	resp := Response{
+		Response: ResponsePayload{
+			Text: "Для вас 1 новых сообщений.",
+			TTS:  "Для вас одно новое сообщение.",
+			Card: &Card{
+				Type:   CardItemsList,
+				Header: &CardHeader{Text: "Сообщения"},
+				Items: []CardItem{
+					{Title: "От ivan", Button: &CardButton{Payload: map[string]any{"index": 1}}},
+				},
+			},
+			Buttons: []Button{
+				{Title: "Прочитай", Hide: true},
+			},
+		},
+		SessionState:    map[string]any{"step": "read"},
+		UserStateUpdate: map[string]any{"dialog": nil},
+		Version:         "1.0",
+	}
+
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"response": {
+			"text": "Для вас 1 новых сообщений.",
+			"tts": "Для вас одно новое сообщение.",
+			"card": {
+				"type": "ItemsList",
+				"header": {"text": "Сообщения"},
+				"items": [{"title": "От ivan", "button": {"payload": {"index": 1}}}]
+			},
+			"buttons": [{"title": "Прочитай", "hide": true}],
+			"end_session": false
+		},
+		"session_state": {"step": "read"},
+		"user_state_update": {"dialog": null},
+		"version": "1.0"
+	}`, string(data))
+}
+
+func TestFixtureIdentifiers(t *testing.T) {
+	skillID := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	userID := regexp.MustCompile(`^[0-9A-F]{64}$`)
+
+	for _, name := range []string{"simple_utterance.json", "new_session.json", "button_pressed.json"} {
+		t.Run(name, func(t *testing.T) {
+			req := decodeFixture(t, name)
+			assert.Regexp(t, skillID, req.Session.SkillID)
+			assert.Regexp(t, userID, req.Session.Application.ApplicationID)
+			for _, id := range []string{req.Session.UserID, req.Session.User.UserID} {
+				if id != "" {
+					assert.Regexp(t, userID, id)
+				}
+			}
+		})
+	}
+}
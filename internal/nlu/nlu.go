@@ -0,0 +1,217 @@
+// Package nlu извлекает из реплики пользователя параметры команд.
+// В первую очередь используются именованные сущности, которые распознала Алиса,
+// а если их нет — собственные разборщики текста команды.
+package nlu
+
+import (
+	"alice-skill/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// порядковые числительные, которыми пользователь называет номер сообщения
+var ordinals = map[string]int{
+	"первое":    1,
+	"второе":    2,
+	"третье":    3,
+	"четвёртое": 4,
+	"четвертое": 4,
+	"пятое":     5,
+	"шестое":    6,
+	"седьмое":   7,
+	"восьмое":   8,
+	"девятое":   9,
+	"десятое":   10,
+}
+
+// служебные слова, которые пропускаются перед именем адресата или пользователя
+var fillerWords = map[string]bool{
+	"сообщение":    true,
+	"пользователю": true,
+	"для":          true,
+	"меня":         true,
+	"как":          true,
+	"под":          true,
+	"именем":       true,
+}
+
+// FindEntity возвращает первую сущность указанного типа
+func FindEntity(u models.SimpleUtterance, typ string) (models.Entity, bool) {
+	for _, e := range u.NLU.Entities {
+		if e.Type == typ {
+			return e, true
+		}
+	}
+	return models.Entity{}, false
+}
+
+// ParseSend извлекает из команды «Отправь Ивану привет» имя адресата и текст сообщения.
+// Адресат берётся из сущности YANDEX.FIO, иначе им считается первое значимое слово после глагола.
+func ParseSend(u models.SimpleUtterance) (username, text string) {
+	tokens := Tokens(u)
+
+	if e, ok := FindEntity(u, models.EntityFIO); ok {
+		if fio, err := e.FIO(); err == nil && fioName(fio) != "" {
+			text = JoinTokens(tokens, e.Tokens.End, len(tokens))
+			if text == "" {
+				// адресат назван после текста: «Отправь привет Ивану»
+				text = JoinTokens(tokens, skipFiller(tokens, 1), e.Tokens.Start)
+			}
+			return fioName(fio), text
+		}
+	}
+
+	// пропускаем глагол и служебные слова, следующее слово — логин адресата
+	i := skipFiller(tokens, 1)
+	if i >= len(tokens) {
+		return "", ""
+	}
+	return tokens[i], JoinTokens(tokens, i+1, len(tokens))
+}
+
+// ParseRead извлекает из команды «Прочитай второе сообщение» порядковый номер сообщения, начиная с единицы.
+// Номер берётся из сущности YANDEX.NUMBER, иначе из числа или порядкового числительного в тексте; по умолчанию 1.
+func ParseRead(u models.SimpleUtterance) int {
+	if e, ok := FindEntity(u, models.EntityNumber); ok {
+		if n, err := e.Number(); err == nil && n >= 1 {
+			return int(n)
+		}
+	}
+
+	for _, token := range Tokens(u) {
+		if n, ok := ordinals[token]; ok {
+			return n
+		}
+		if n, err := strconv.Atoi(token); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return 1
+}
+
+// ParseRegister извлекает из команды «Зарегистрируй меня как Иван» желаемое имя пользователя.
+// Имя берётся из сущности YANDEX.FIO, иначе им считается первое значимое слово после глагола.
+func ParseRegister(u models.SimpleUtterance) string {
+	if e, ok := FindEntity(u, models.EntityFIO); ok {
+		if fio, err := e.FIO(); err == nil && fioName(fio) != "" {
+			return fioName(fio)
+		}
+	}
+
+	tokens := Tokens(u)
+	i := skipFiller(tokens, 1)
+	if i >= len(tokens) {
+		return ""
+	}
+	return tokens[i]
+}
+
+// ParseName извлекает имя из короткой реплики вроде «Ивану» или «Иван Петров»:
+// из сущности YANDEX.FIO, а если её нет — первое значимое слово
+func ParseName(u models.SimpleUtterance) string {
+	if e, ok := FindEntity(u, models.EntityFIO); ok {
+		if fio, err := e.FIO(); err == nil && fioName(fio) != "" {
+			return fioName(fio)
+		}
+	}
+
+	tokens := Tokens(u)
+	i := skipFiller(tokens, 0)
+	if i >= len(tokens) {
+		return ""
+	}
+	return tokens[i]
+}
+
+// ParseDateTime возвращает момент времени из сущности YANDEX.DATETIME и её положение в списке токенов.
+// Второе значение равно false, если сущности нет или в ней не указано время суток.
+func ParseDateTime(u models.SimpleUtterance, now time.Time) (time.Time, models.TokenSpan, bool) {
+	e, ok := FindEntity(u, models.EntityDateTime)
+	if !ok {
+		return time.Time{}, models.TokenSpan{}, false
+	}
+
+	dt, err := e.DateTime()
+	if err != nil || (dt.Hour == nil && dt.Minute == nil) {
+		return time.Time{}, models.TokenSpan{}, false
+	}
+	return ResolveDateTime(dt, now), e.Tokens, true
+}
+
+// ResolveDateTime превращает относительные и абсолютные части YANDEX.DATETIME в момент времени в часовом поясе now.
+// Неуказанные части даты берутся из now; если указан только час, минуты обнуляются.
+func ResolveDateTime(dt models.DateTime, now time.Time) time.Time {
+	year, month, day := now.Date()
+	hour, minute := now.Hour(), now.Minute()
+
+	if dt.Year != nil {
+		year = resolvePart(year, *dt.Year, dt.YearIsRelative)
+	}
+	if dt.Month != nil {
+		month = time.Month(resolvePart(int(month), *dt.Month, dt.MonthIsRelative))
+	}
+	if dt.Day != nil {
+		day = resolvePart(day, *dt.Day, dt.DayIsRelative)
+	}
+	if dt.Hour != nil {
+		hour = resolvePart(hour, *dt.Hour, dt.HourIsRelative)
+		if !dt.HourIsRelative && dt.Minute == nil {
+			minute = 0
+		}
+	}
+	if dt.Minute != nil {
+		minute = resolvePart(minute, *dt.Minute, dt.MinuteIsRelative)
+	}
+
+	// time.Date сам нормализует переполнение, например 25 часов или 32-е число
+	return time.Date(year, month, day, hour, minute, 0, 0, now.Location())
+}
+
+func resolvePart(current, value int, relative bool) int {
+	if relative {
+		return current + value
+	}
+	return value
+}
+
+// Tokens возвращает токены реплики: от Алисы, а если их нет — полученные разбиением команды
+func Tokens(u models.SimpleUtterance) []string {
+	if len(u.NLU.Tokens) > 0 {
+		return u.NLU.Tokens
+	}
+	return strings.Fields(strings.ToLower(u.Command))
+}
+
+// JoinTokens склеивает токены в диапазоне [from, to) через пробел
+func JoinTokens(tokens []string, from, to int) string {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(tokens) {
+		to = len(tokens)
+	}
+	if from >= to {
+		return ""
+	}
+	return strings.Join(tokens[from:to], " ")
+}
+
+func skipFiller(tokens []string, from int) int {
+	i := from
+	for i < len(tokens) && fillerWords[tokens[i]] {
+		i++
+	}
+	return i
+}
+
+// fioName собирает имя пользователя из частей ФИО в именительном падеже
+func fioName(fio models.FIO) string {
+	parts := make([]string, 0, 2)
+	for _, p := range []string{fio.FirstName, fio.LastName} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
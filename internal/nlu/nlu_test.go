@@ -0,0 +1,109 @@
+package nlu
+
+import (
+	"alice-skill/internal/models"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSend(t *testing.T) {
+	testCases := []struct {
+		name          string
+		utterance     models.SimpleUtterance
+		wantRecipient string
+		wantText      string
+	}{
+		{
+			name: "fio_entity",
+			utterance: models.SimpleUtterance{
+				Command: "отправь ивану петрову привет как дела",
+				NLU: models.NLU{
+					Tokens: []string{"отправь", "ивану", "петрову", "привет", "как", "дела"},
+					Entities: []models.Entity{{
+						Type:   models.EntityFIO,
+						Tokens: models.TokenSpan{Start: 1, End: 3},
+						Value:  json.RawMessage(`{"first_name": "иван", "last_name": "петров"}`),
+					}},
+				},
+			},
+			wantRecipient: "иван петров",
+			wantText:      "привет как дела",
+		},
+		{
+			name: "fio_entity_after_text",
+			utterance: models.SimpleUtterance{
+				Command: "отправь привет ивану",
+				NLU: models.NLU{
+					Tokens: []string{"отправь", "привет", "ивану"},
+					Entities: []models.Entity{{
+						Type:   models.EntityFIO,
+						Tokens: models.TokenSpan{Start: 2, End: 3},
+						Value:  json.RawMessage(`{"first_name": "иван"}`),
+					}},
+				},
+			},
+			wantRecipient: "иван",
+			wantText:      "привет",
+		},
+		{
+			name:          "fallback_login",
+			utterance:     models.SimpleUtterance{Command: "Отправь сообщение ivan42 встречаемся в шесть"},
+			wantRecipient: "ivan42",
+			wantText:      "встречаемся в шесть",
+		},
+		{
+			name:      "nothing_to_extract",
+			utterance: models.SimpleUtterance{Command: "Отправь сообщение"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recipient, text := ParseSend(tc.utterance)
+			assert.Equal(t, tc.wantRecipient, recipient)
+			assert.Equal(t, tc.wantText, text)
+		})
+	}
+}
+
+func TestParseRead(t *testing.T) {
+	number := models.SimpleUtterance{
+		Command: "прочитай сообщение номер 3",
+		NLU: models.NLU{
+			Tokens:   []string{"прочитай", "сообщение", "номер", "3"},
+			Entities: []models.Entity{{Type: models.EntityNumber, Tokens: models.TokenSpan{Start: 3, End: 4}, Value: json.RawMessage(`3`)}},
+		},
+	}
+	assert.Equal(t, 3, ParseRead(number))
+	assert.Equal(t, 2, ParseRead(models.SimpleUtterance{Command: "Прочитай второе сообщение"}))
+	assert.Equal(t, 1, ParseRead(models.SimpleUtterance{Command: "Прочитай сообщение"}))
+}
+
+func TestParseRegister(t *testing.T) {
+	assert.Equal(t, "ivan", ParseRegister(models.SimpleUtterance{Command: "Зарегистрируй меня как ivan"}))
+	assert.Equal(t, "", ParseRegister(models.SimpleUtterance{Command: "Зарегистрируй меня"}))
+}
+
+func TestResolveDateTime(t *testing.T) {
+	now := time.Date(2024, time.September, 6, 12, 34, 56, 0, time.UTC)
+	ptr := func(v int) *int { return &v }
+
+	// «завтра в 9»
+	assert.Equal(t,
+		time.Date(2024, time.September, 7, 9, 0, 0, 0, time.UTC),
+		ResolveDateTime(models.DateTime{Day: ptr(1), DayIsRelative: true, Hour: ptr(9)}, now),
+	)
+	// «через 2 часа»
+	assert.Equal(t,
+		time.Date(2024, time.September, 6, 14, 34, 0, 0, time.UTC),
+		ResolveDateTime(models.DateTime{Hour: ptr(2), HourIsRelative: true}, now),
+	)
+	// «31 декабря в 23:30»
+	assert.Equal(t,
+		time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC),
+		ResolveDateTime(models.DateTime{Month: ptr(12), Day: ptr(31), Hour: ptr(23), Minute: ptr(30)}, now),
+	)
+}
@@ -0,0 +1,172 @@
+// Package ratelimit ограничивает частоту команд алгоритмом token bucket.
+// Корзины заводятся отдельно для каждого пользователя и интента и для каждого IP-адреса,
+// а их состояние хранится за интерфейсом Buckets, чтобы его можно было перенести из памяти процесса в хранилище.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ключи ограничений, которые не являются именами интентов
+const (
+	KeyDefault = "default" // ограничение для интентов без собственного
+	KeyIP      = "ip"      // общее ограничение на IP-адрес
+)
+
+// Limit описывает корзину: скорость пополнения и ёмкость. Нулевая скорость отключает ограничение.
+type Limit struct {
+	Rate  float64 // токенов в секунду
+	Burst int     // сколько команд можно выполнить подряд
+}
+
+// Buckets хранит состояние корзин
+type Buckets interface {
+	// Take забирает токен из корзины key и сообщает, был ли он
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (bool, error)
+}
+
+// Limiter проверяет, не превышены ли ограничения для команды
+type Limiter struct {
+	buckets Buckets
+	limits  map[string]Limit
+}
+
+// New создаёт ограничитель; limits содержит ограничения по именам интентов и ключам KeyDefault и KeyIP
+func New(buckets Buckets, limits map[string]Limit) *Limiter {
+	return &Limiter{
+		buckets: buckets,
+		limits:  limits,
+	}
+}
+
+// Allow забирает токены для команды интента intentName от пользователя userID с адреса ip.
+// Пустые userID или ip не ограничиваются.
+// Сначала проверяется корзина пользователя: отклонённые ею команды не расходуют корзину IP-адреса,
+// которую делят все пользователи за этим адресом.
+func (l *Limiter) Allow(ctx context.Context, intentName, userID, ip string) (bool, error) {
+	now := time.Now()
+
+	// интенты без собственного ограничения делят одну корзину пользователя
+	key := intentName
+	limit, ok := l.limits[intentName]
+	if !ok {
+		key, limit = KeyDefault, l.limits[KeyDefault]
+	}
+	if userID != "" && limit.Rate > 0 {
+		ok, err := l.buckets.Take(ctx, "user:"+key+":"+userID, limit, now)
+		if err != nil || !ok {
+			return ok, err
+		}
+	}
+
+	if limit := l.limits[KeyIP]; ip != "" && limit.Rate > 0 {
+		return l.buckets.Take(ctx, "ip:"+ip, limit, now)
+	}
+	return true, nil
+}
+
+// ParseLimits разбирает ограничения вида «send=10/m:5,default=1/s:20,ip=100/s:200»:
+// имя интента, число команд за секунду (s), минуту (m) или час (h) и, после двоеточия, ёмкость корзины.
+// Если ёмкость не указана, она равна числу команд.
+func ParseLimits(spec string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("rate limit %q must look like name=count/unit[:burst]", item)
+		}
+		value, burstValue, hasBurst := strings.Cut(value, ":")
+		countValue, unit, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q must look like name=count/unit[:burst]", item)
+		}
+
+		count, err := strconv.Atoi(countValue)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("rate limit %q: count must be a non-negative integer", item)
+		}
+		per, ok := map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour}[unit]
+		if !ok {
+			return nil, fmt.Errorf("rate limit %q: unknown unit %q, want s, m or h", item, unit)
+		}
+		burst := count
+		if hasBurst {
+			if burst, err = strconv.Atoi(burstValue); err != nil || burst < 1 {
+				return nil, fmt.Errorf("rate limit %q: burst must be a positive integer", item)
+			}
+		}
+
+		limits[strings.TrimSpace(name)] = Limit{Rate: float64(count) / per.Seconds(), Burst: burst}
+	}
+	return limits, nil
+}
+
+// MemoryBuckets хранит корзины в памяти процесса
+type MemoryBuckets struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // момент, когда корзина снова наполнится и её можно забыть
+}
+
+// период, с которым из памяти удаляются наполнившиеся корзины
+const sweepInterval = time.Minute
+
+// NewMemoryBuckets создаёт хранилище корзин в памяти процесса
+func NewMemoryBuckets() *MemoryBuckets {
+	return &MemoryBuckets{
+		buckets:   make(map[string]bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *MemoryBuckets) Take(_ context.Context, key string, limit Limit, now time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// полная корзина ничем не отличается от отсутствующей, поэтому такие корзины периодически забываем
+	if now.Sub(m.lastSweep) > sweepInterval {
+		for k, b := range m.buckets {
+			if now.After(b.full) {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = bucket{tokens: float64(limit.Burst), updated: now}
+	}
+
+	// пополняем корзину за время, прошедшее с прошлой команды
+	b.tokens += now.Sub(b.updated).Seconds() * limit.Rate
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second)))
+
+	m.buckets[key] = b
+	return allowed, nil
+}
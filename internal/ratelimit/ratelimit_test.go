@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBuckets(t *testing.T) {
+	ctx := context.Background()
+	b := NewMemoryBuckets()
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	// корзина позволяет выполнить Burst команд подряд
+	for i := 0; i < 2; i++ {
+		ok, err := b.Take(ctx, "key", limit, now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, _ := b.Take(ctx, "key", limit, now)
+	assert.False(t, ok)
+
+	// другие ключи не затронуты
+	ok, _ = b.Take(ctx, "other", limit, now)
+	assert.True(t, ok)
+
+	// через секунду появляется один токен
+	ok, _ = b.Take(ctx, "key", limit, now.Add(time.Second))
+	assert.True(t, ok)
+	ok, _ = b.Take(ctx, "key", limit, now.Add(time.Second))
+	assert.False(t, ok)
+}
+
+func TestLimiterAllow(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryBuckets(), map[string]Limit{
+		"send":     {Rate: 0.001, Burst: 1},
+		KeyDefault: {Rate: 0.001, Burst: 3},
+		KeyIP:      {Rate: 0.001, Burst: 5},
+	})
+
+	allow := func(intentName, userID, ip string) bool {
+		ok, err := l.Allow(ctx, intentName, userID, ip)
+		require.NoError(t, err)
+		return ok
+	}
+
+	// у отправки собственная, более строгая корзина
+	assert.True(t, allow("send", "user1", ""))
+	assert.False(t, allow("send", "user1", ""))
+	assert.True(t, allow("send", "user2", ""))
+
+	// остальные интенты делят корзину по умолчанию
+	assert.True(t, allow("read", "user1", ""))
+	assert.True(t, allow("done", "user1", ""))
+	assert.True(t, allow("read", "user1", ""))
+	assert.False(t, allow("status", "user1", ""))
+
+	// ограничение на IP действует для всех пользователей и интентов
+	for i := 0; i < 5; i++ {
+		assert.True(t, allow("read", "", "10.0.0.1"))
+	}
+	assert.False(t, allow("read", "user3", "10.0.0.1"))
+	assert.True(t, allow("read", "user3", "10.0.0.2"))
+}
+
+func TestLimiterNoisyUserKeepsIPCapacity(t *testing.T) {
+	ctx := context.Background()
+	l := New(NewMemoryBuckets(), map[string]Limit{
+		"send": {Rate: 0.001, Burst: 1},
+		KeyIP:  {Rate: 0.001, Burst: 2},
+	})
+
+	// команды, отклонённые корзиной пользователя, не расходуют общую корзину адреса
+	ok, err := l.Allow(ctx, "send", "noisy", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	for i := 0; i < 10; i++ {
+		ok, err = l.Allow(ctx, "send", "noisy", "10.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	ok, err = l.Allow(ctx, "send", "quiet", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("send=10/m:5, default=2/s, ip=3600/h:100")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"send":     {Rate: 10.0 / 60, Burst: 5},
+		KeyDefault: {Rate: 2, Burst: 2},
+		KeyIP:      {Rate: 1, Burst: 100},
+	}, limits)
+
+	for _, spec := range []string{"send", "send=10", "send=x/m", "send=10/d", "send=10/m:0", "=1/s"} {
+		_, err := ParseLimits(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,203 @@
+// Package reminder содержит разбор голосовых команд напоминаний и расчёт времени их повторения
+package reminder
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/nlu"
+	"alice-skill/internal/store"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSnooze — время, на которое откладывается напоминание, если пользователь не назвал другое
+const DefaultSnooze = 10 * time.Minute
+
+var (
+	// время вида «в 18:00», «в 18.00», «в 18 00» или «в 9»
+	hourRe   = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?$`)
+	minuteRe = regexp.MustCompile(`^\d{2}$`)
+	// отложи [напоминание] на 15 минут / на 1 час
+	snoozeRe = regexp.MustCompile(`на\s+(\d+)\s+(минут|минуты|минуту|час|часа|часов)`)
+)
+
+// словарь фраз, которыми пользователь описывает повторение
+var recurrencePhrases = map[string]store.Recurrence{
+	"каждый день":    store.RecurrenceDaily,
+	"ежедневно":      store.RecurrenceDaily,
+	"по будням":      store.RecurrenceWeekdays,
+	"по будним дням": store.RecurrenceWeekdays,
+	"каждую неделю":  store.RecurrenceWeekly,
+	"еженедельно":    store.RecurrenceWeekly,
+}
+
+// слова команды, которые не входят в текст напоминания
+var serviceWords = map[string]bool{
+	"напомни":     true,
+	"мне":         true,
+	"каждый":      true,
+	"каждую":      true,
+	"день":        true,
+	"неделю":      true,
+	"ежедневно":   true,
+	"еженедельно": true,
+	"по":          true,
+	"будням":      true,
+	"будним":      true,
+	"дням":        true,
+}
+
+// ParseTime извлекает из реплики время напоминания.
+// Время берётся из сущности YANDEX.DATETIME, а если Алиса её не распознала — из оборота «в 18:00» в тексте команды.
+func ParseTime(u models.SimpleUtterance, now time.Time) (time.Time, bool) {
+	if due, _, ok := nlu.ParseDateTime(u, now); ok {
+		return due, true
+	}
+
+	tokens := nlu.Tokens(u)
+	span, ok := timeSpan(tokens)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	m := hourRe.FindStringSubmatch(tokens[span.Start+1])
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	switch {
+	case m[2] != "":
+		minute, _ = strconv.Atoi(m[2])
+	case span.End-span.Start == 3:
+		minute, _ = strconv.Atoi(tokens[span.Start+2])
+	}
+	if hour > 23 || minute > 59 {
+		return time.Time{}, false
+	}
+
+	return time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location()), true
+}
+
+// ParseText извлекает из реплики текст напоминания: всё, кроме глагола, описания повторения и времени
+func ParseText(u models.SimpleUtterance) string {
+	tokens := nlu.Tokens(u)
+
+	// время исключаем из текста, даже если в нём назван только день
+	span, ok := timeSpan(tokens)
+	if e, found := nlu.FindEntity(u, models.EntityDateTime); found {
+		span, ok = e.Tokens, true
+	}
+
+	rest := tokens
+	if ok {
+		// текст обычно идёт после времени, но может быть назван и до него
+		rest = tokens[min(span.End, len(tokens)):]
+		if len(rest) == 0 {
+			rest = tokens[:min(span.Start, len(tokens))]
+			if len(rest) > 0 && rest[len(rest)-1] == "в" {
+				rest = rest[:len(rest)-1]
+			}
+		}
+	}
+
+	// отбрасываем глагол, обращение и описание повторения
+	for len(rest) > 0 && serviceWords[rest[0]] {
+		rest = rest[1:]
+	}
+	return strings.Join(rest, " ")
+}
+
+// ParseRecurrence ищет в команде фразу, описывающую повторение
+func ParseRecurrence(command string) store.Recurrence {
+	command = normalize(command)
+	for phrase, rec := range recurrencePhrases {
+		if strings.Contains(command, phrase) {
+			return rec
+		}
+	}
+	return store.RecurrenceNone
+}
+
+// Schedule переносит уже прошедшее время на следующий день, а будничное напоминание — с выходных на понедельник
+func Schedule(due, now time.Time, rec store.Recurrence) time.Time {
+	if !due.After(now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	if rec == store.RecurrenceWeekdays {
+		due = skipWeekend(due)
+	}
+	return due
+}
+
+// timeSpan ищет в токенах оборот «в 18:00» или «в 18 00» и возвращает его положение
+func timeSpan(tokens []string) (models.TokenSpan, bool) {
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i] != "в" || !hourRe.MatchString(tokens[i+1]) {
+			continue
+		}
+		end := i + 2
+		// минуты могут прийти отдельным токеном, если Алиса убрала двоеточие
+		if !strings.ContainsAny(tokens[i+1], ":.") && end < len(tokens) && minuteRe.MatchString(tokens[end]) {
+			end++
+		}
+		return models.TokenSpan{Start: i, End: end}, true
+	}
+	return models.TokenSpan{}, false
+}
+
+// ParseSnooze извлекает из команды «Отложи напоминание на 15 минут» длительность откладывания
+func ParseSnooze(command string) time.Duration {
+	m := snoozeRe.FindStringSubmatch(normalize(command))
+	if m == nil {
+		return DefaultSnooze
+	}
+
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return DefaultSnooze
+	}
+
+	if strings.HasPrefix(m[2], "час") {
+		return time.Duration(n) * time.Hour
+	}
+	return time.Duration(n) * time.Minute
+}
+
+// Next возвращает первое после now срабатывание повторяющегося напоминания по его расписанию scheduled.
+// Календарь считается в часовом поясе now: напоминание на 9:00 остаётся на 9:00 у пользователя,
+// а будни и выходные определяются по его дате, а не по UTC.
+// Для однократных напоминаний второе значение равно false.
+func Next(rec store.Recurrence, scheduled, now time.Time) (time.Time, bool) {
+	days := 1
+	switch rec {
+	case store.RecurrenceDaily, store.RecurrenceWeekdays:
+	case store.RecurrenceWeekly:
+		days = 7
+	default:
+		return time.Time{}, false
+	}
+
+	// расписание могло устареть на несколько периодов, если пользователь долго не отвечал
+	next := scheduled.In(now.Location())
+	for {
+		next = next.AddDate(0, 0, days)
+		if rec == store.RecurrenceWeekdays {
+			next = skipWeekend(next)
+		}
+		if next.After(now) {
+			return next, true
+		}
+	}
+}
+
+// skipWeekend переносит момент времени с выходных на ближайший понедельник
+func skipWeekend(t time.Time) time.Time {
+	for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// normalize приводит команду к нижнему регистру и схлопывает пробелы
+func normalize(command string) string {
+	return strings.Join(strings.Fields(strings.ToLower(command)), " ")
+}
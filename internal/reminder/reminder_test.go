@@ -0,0 +1,173 @@
+package reminder
+
+import (
+	"alice-skill/internal/models"
+	"alice-skill/internal/store"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReminder(t *testing.T) {
+	// пятница, 12:00
+	now := time.Date(2024, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		command  string
+		wantDue  time.Time
+		wantRec  store.Recurrence
+		wantText string
+		wantOK   bool
+	}{
+		{
+			name:     "once_today",
+			command:  "Напомни мне в 18:00 купить хлеб",
+			wantDue:  time.Date(2024, time.September, 6, 18, 0, 0, 0, time.UTC),
+			wantText: "купить хлеб",
+			wantOK:   true,
+		},
+		{
+			name:     "once_tomorrow",
+			command:  "напомни в 9 позвонить маме",
+			wantDue:  time.Date(2024, time.September, 7, 9, 0, 0, 0, time.UTC),
+			wantText: "позвонить маме",
+			wantOK:   true,
+		},
+		{
+			name:     "daily",
+			command:  "Напомни мне каждый день в 21.30 выпить таблетку",
+			wantDue:  time.Date(2024, time.September, 6, 21, 30, 0, 0, time.UTC),
+			wantRec:  store.RecurrenceDaily,
+			wantText: "выпить таблетку",
+			wantOK:   true,
+		},
+		{
+			name:     "weekdays_skip_weekend",
+			command:  "Напомни мне по будням в 8:15 зарядка",
+			wantDue:  time.Date(2024, time.September, 9, 8, 15, 0, 0, time.UTC),
+			wantRec:  store.RecurrenceWeekdays,
+			wantText: "зарядка",
+			wantOK:   true,
+		},
+		{
+			name:    "bad_time",
+			command: "Напомни мне в 25:00 купить хлеб",
+		},
+		{
+			name:    "no_time",
+			command: "Напомни мне купить хлеб",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := models.SimpleUtterance{Command: tc.command}
+			due, ok := ParseTime(u, now)
+			require.Equal(t, tc.wantOK, ok)
+			if !ok {
+				return
+			}
+
+			rec := ParseRecurrence(u.Command)
+			due = Schedule(due, now, rec)
+			assert.True(t, tc.wantDue.Equal(due), "got due %s", due)
+			assert.Equal(t, tc.wantRec, rec)
+			assert.Equal(t, tc.wantText, ParseText(u))
+		})
+	}
+}
+
+func TestParseReminderWithEntities(t *testing.T) {
+	// пятница, 12:00
+	now := time.Date(2024, time.September, 6, 12, 0, 0, 0, time.UTC)
+
+	// «напомни мне завтра в 9 полить цветы»: время и дату распознала Алиса
+	u := models.SimpleUtterance{
+		Command: "напомни мне завтра в 9 полить цветы",
+		NLU: models.NLU{
+			Tokens: []string{"напомни", "мне", "завтра", "в", "9", "полить", "цветы"},
+			Entities: []models.Entity{
+				{
+					Type:   models.EntityDateTime,
+					Tokens: models.TokenSpan{Start: 2, End: 5},
+					Value:  json.RawMessage(`{"day": 1, "day_is_relative": true, "hour": 9}`),
+				},
+			},
+		},
+	}
+
+	due, ok := ParseTime(u, now)
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2024, time.September, 7, 9, 0, 0, 0, time.UTC), Schedule(due, now, store.RecurrenceNone))
+	assert.Equal(t, store.RecurrenceNone, ParseRecurrence(u.Command))
+	assert.Equal(t, "полить цветы", ParseText(u))
+}
+
+func TestNext(t *testing.T) {
+	// пользователь во Владивостоке: его утро — ещё вчерашний вечер по UTC
+	vladivostok := time.FixedZone("UTC+10", 10*60*60)
+	// пятница, 8:00 у пользователя
+	scheduled := time.Date(2024, time.September, 6, 8, 0, 0, 0, vladivostok)
+
+	testCases := []struct {
+		name string
+		rec  store.Recurrence
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "daily",
+			rec:  store.RecurrenceDaily,
+			now:  scheduled.Add(time.Minute),
+			want: time.Date(2024, time.September, 7, 8, 0, 0, 0, vladivostok),
+		},
+		{
+			// отложенное на полчаса напоминание повторяется по расписанию, а не от времени откладывания
+			name: "daily_after_snooze",
+			rec:  store.RecurrenceDaily,
+			now:  scheduled.Add(40 * time.Minute),
+			want: time.Date(2024, time.September, 7, 8, 0, 0, 0, vladivostok),
+		},
+		{
+			// пользователь отметил напоминание через несколько дней: следующее срабатывание не должно быть в прошлом
+			name: "daily_stale",
+			rec:  store.RecurrenceDaily,
+			now:  time.Date(2024, time.September, 9, 12, 0, 0, 0, vladivostok),
+			want: time.Date(2024, time.September, 10, 8, 0, 0, 0, vladivostok),
+		},
+		{
+			// по UTC суббота 8:00 у пользователя — ещё пятница, но переносить нужно по его календарю
+			name: "weekdays_in_user_location",
+			rec:  store.RecurrenceWeekdays,
+			now:  scheduled.Add(time.Minute),
+			want: time.Date(2024, time.September, 9, 8, 0, 0, 0, vladivostok),
+		},
+		{
+			name: "weekly",
+			rec:  store.RecurrenceWeekly,
+			now:  scheduled.Add(time.Minute),
+			want: time.Date(2024, time.September, 13, 8, 0, 0, 0, vladivostok),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, ok := Next(tc.rec, scheduled.UTC(), tc.now)
+			require.True(t, ok)
+			assert.Equal(t, tc.want, next)
+		})
+	}
+
+	_, ok := Next(store.RecurrenceNone, scheduled, scheduled)
+	assert.False(t, ok)
+}
+
+func TestParseSnooze(t *testing.T) {
+	assert.Equal(t, DefaultSnooze, ParseSnooze("Отложи напоминание"))
+	assert.Equal(t, 15*time.Minute, ParseSnooze("Отложи напоминание на 15 минут"))
+	assert.Equal(t, 2*time.Hour, ParseSnooze("отложи на 2 часа"))
+}
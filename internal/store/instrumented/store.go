@@ -0,0 +1,178 @@
+// Package instrumented оборачивает любую реализацию store.Store, измеряет длительность её вызовов и трассирует их.
+package instrumented
+
+import (
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/store"
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// результаты вызова хранилища в метриках
+const (
+	resultOK       = "ok"
+	resultNotFound = "not_found"
+	resultConflict = "conflict"
+	resultError    = "error"
+)
+
+// tracer создаёт спаны вызовов хранилища, по одному на вызов
+var tracer = otel.Tracer("alice-skill/internal/store/instrumented")
+
+// Store реализует store.Store, передавая вызовы next, записывая их длительность в метрику StoreDuration
+// и оборачивая каждый вызов в спан store.<метод>
+type Store struct {
+	next store.Store
+}
+
+// NewStore возвращает хранилище, измеряющее вызовы next
+func NewStore(next store.Store) *Store {
+	return &Store{next: next}
+}
+
+// observe записывает длительность вызова method с начала start и его результат, затем завершает спан вызова
+func observe(span trace.Span, method string, start time.Time, err error) {
+	defer span.End()
+
+	result := resultOK
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		result = resultNotFound
+	case errors.Is(err, store.ErrConflict):
+		result = resultConflict
+	default:
+		result = resultError
+		// отсутствие записи и конфликт — ожидаемые исходы, ошибкой спана отмечаем только сбои
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	metrics.StoreDuration.WithLabelValues(method, result).Observe(time.Since(start).Seconds())
+	span.SetAttributes(attribute.String("store.result", result))
+}
+
+func (s *Store) FindRecipient(ctx context.Context, username string) (string, error) {
+	ctx, span := tracer.Start(ctx, "store.FindRecipient")
+	start := time.Now()
+	userID, err := s.next.FindRecipient(ctx, username)
+	observe(span, "FindRecipient", start, err)
+	return userID, err
+}
+
+func (s *Store) ListMessages(ctx context.Context, userID string) ([]store.Message, error) {
+	ctx, span := tracer.Start(ctx, "store.ListMessages")
+	start := time.Now()
+	messages, err := s.next.ListMessages(ctx, userID)
+	observe(span, "ListMessages", start, err)
+	return messages, err
+}
+
+func (s *Store) GetMessage(ctx context.Context, id int64) (*store.Message, error) {
+	ctx, span := tracer.Start(ctx, "store.GetMessage")
+	start := time.Now()
+	message, err := s.next.GetMessage(ctx, id)
+	observe(span, "GetMessage", start, err)
+	return message, err
+}
+
+func (s *Store) SaveMessages(ctx context.Context, messages ...store.Message) error {
+	ctx, span := tracer.Start(ctx, "store.SaveMessages")
+	start := time.Now()
+	err := s.next.SaveMessages(ctx, messages...)
+	observe(span, "SaveMessages", start, err)
+	return err
+}
+
+func (s *Store) RegisterUser(ctx context.Context, userID, username string) error {
+	ctx, span := tracer.Start(ctx, "store.RegisterUser")
+	start := time.Now()
+	err := s.next.RegisterUser(ctx, userID, username)
+	observe(span, "RegisterUser", start, err)
+	return err
+}
+
+func (s *Store) SaveReminder(ctx context.Context, reminder store.Reminder) error {
+	ctx, span := tracer.Start(ctx, "store.SaveReminder")
+	start := time.Now()
+	err := s.next.SaveReminder(ctx, reminder)
+	observe(span, "SaveReminder", start, err)
+	return err
+}
+
+func (s *Store) ListReminders(ctx context.Context, userID string, before time.Time) ([]store.Reminder, error) {
+	ctx, span := tracer.Start(ctx, "store.ListReminders")
+	start := time.Now()
+	reminders, err := s.next.ListReminders(ctx, userID, before)
+	observe(span, "ListReminders", start, err)
+	return reminders, err
+}
+
+func (s *Store) RescheduleReminder(ctx context.Context, id int64, dueAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "store.RescheduleReminder")
+	start := time.Now()
+	err := s.next.RescheduleReminder(ctx, id, dueAt)
+	observe(span, "RescheduleReminder", start, err)
+	return err
+}
+
+func (s *Store) AdvanceReminder(ctx context.Context, id int64, scheduledAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "store.AdvanceReminder")
+	start := time.Now()
+	err := s.next.AdvanceReminder(ctx, id, scheduledAt)
+	observe(span, "AdvanceReminder", start, err)
+	return err
+}
+
+func (s *Store) CompleteReminder(ctx context.Context, id int64) error {
+	ctx, span := tracer.Start(ctx, "store.CompleteReminder")
+	start := time.Now()
+	err := s.next.CompleteReminder(ctx, id)
+	observe(span, "CompleteReminder", start, err)
+	return err
+}
+
+func (s *Store) GetSettings(ctx context.Context, userID string) (*store.Settings, error) {
+	ctx, span := tracer.Start(ctx, "store.GetSettings")
+	start := time.Now()
+	settings, err := s.next.GetSettings(ctx, userID)
+	observe(span, "GetSettings", start, err)
+	return settings, err
+}
+
+func (s *Store) SaveSettings(ctx context.Context, settings store.Settings) error {
+	ctx, span := tracer.Start(ctx, "store.SaveSettings")
+	start := time.Now()
+	err := s.next.SaveSettings(ctx, settings)
+	observe(span, "SaveSettings", start, err)
+	return err
+}
+
+func (s *Store) GetUserState(ctx context.Context, userID string) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "store.GetUserState")
+	start := time.Now()
+	state, err := s.next.GetUserState(ctx, userID)
+	observe(span, "GetUserState", start, err)
+	return state, err
+}
+
+func (s *Store) SaveUserState(ctx context.Context, userID string, state []byte) error {
+	ctx, span := tracer.Start(ctx, "store.SaveUserState")
+	start := time.Now()
+	err := s.next.SaveUserState(ctx, userID, state)
+	observe(span, "SaveUserState", start, err)
+	return err
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "store.Ping")
+	start := time.Now()
+	err := s.next.Ping(ctx)
+	observe(span, "Ping", start, err)
+	return err
+}
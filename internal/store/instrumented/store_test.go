@@ -0,0 +1,91 @@
+package instrumented
+
+import (
+	"alice-skill/internal/metrics"
+	"alice-skill/internal/store"
+	"alice-skill/internal/store/mock"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// observations возвращает число измерений метрики StoreDuration для метода и результата
+func observations(t *testing.T, method, result string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	require.NoError(t, metrics.StoreDuration.WithLabelValues(method, result).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	next := mock.NewMockStore(ctrl)
+	s := NewStore(next)
+	ctx := context.Background()
+
+	next.EXPECT().FindRecipient(gomock.Any(), "ivan").Return("user2", nil)
+	next.EXPECT().GetSettings(gomock.Any(), "user1").Return(nil, store.ErrNotFound)
+
+	ok := observations(t, "FindRecipient", resultOK)
+	notFound := observations(t, "GetSettings", resultNotFound)
+
+	// декоратор возвращает результаты хранилища без изменений
+	userID, err := s.FindRecipient(ctx, "ivan")
+	require.NoError(t, err)
+	assert.Equal(t, "user2", userID)
+
+	_, err = s.GetSettings(ctx, "user1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	assert.Equal(t, ok+1, observations(t, "FindRecipient", resultOK))
+	assert.Equal(t, notFound+1, observations(t, "GetSettings", resultNotFound))
+}
+
+func TestStoreSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctrl := gomock.NewController(t)
+	next := mock.NewMockStore(ctrl)
+	s := NewStore(next)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	// спан вызова передаётся реализации в контексте
+	next.EXPECT().FindRecipient(gomock.Any(), "ivan").DoAndReturn(func(ctx context.Context, _ string) (string, error) {
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		return "", store.ErrNotFound
+	})
+	next.EXPECT().SaveMessages(gomock.Any()).Return(errors.New("connection refused"))
+
+	_, err := s.FindRecipient(ctx, "ivan")
+	require.ErrorIs(t, err, store.ErrNotFound)
+	require.Error(t, s.SaveMessages(ctx))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	assert.Equal(t, "store.FindRecipient", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	// запись не найдена — ожидаемый исход, а не сбой
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	assert.Equal(t, "store.SaveMessages", spans[1].Name())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
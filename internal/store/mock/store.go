@@ -8,6 +8,7 @@ import (
 	store "alice-skill/internal/store"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,34 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// AdvanceReminder mocks base method.
+func (m *MockStore) AdvanceReminder(ctx context.Context, id int64, scheduledAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceReminder", ctx, id, scheduledAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdvanceReminder indicates an expected call of AdvanceReminder.
+func (mr *MockStoreMockRecorder) AdvanceReminder(ctx, id, scheduledAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceReminder", reflect.TypeOf((*MockStore)(nil).AdvanceReminder), ctx, id, scheduledAt)
+}
+
+// CompleteReminder mocks base method.
+func (m *MockStore) CompleteReminder(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteReminder", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteReminder indicates an expected call of CompleteReminder.
+func (mr *MockStoreMockRecorder) CompleteReminder(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteReminder", reflect.TypeOf((*MockStore)(nil).CompleteReminder), ctx, id)
+}
+
 // FindRecipient mocks base method.
 func (m *MockStore) FindRecipient(ctx context.Context, username string) (string, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +94,36 @@ func (mr *MockStoreMockRecorder) GetMessage(ctx, id interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMessage", reflect.TypeOf((*MockStore)(nil).GetMessage), ctx, id)
 }
 
+// GetSettings mocks base method.
+func (m *MockStore) GetSettings(ctx context.Context, userID string) (*store.Settings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSettings", ctx, userID)
+	ret0, _ := ret[0].(*store.Settings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSettings indicates an expected call of GetSettings.
+func (mr *MockStoreMockRecorder) GetSettings(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSettings", reflect.TypeOf((*MockStore)(nil).GetSettings), ctx, userID)
+}
+
+// GetUserState mocks base method.
+func (m *MockStore) GetUserState(ctx context.Context, userID string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserState", ctx, userID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserState indicates an expected call of GetUserState.
+func (mr *MockStoreMockRecorder) GetUserState(ctx, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserState", reflect.TypeOf((*MockStore)(nil).GetUserState), ctx, userID)
+}
+
 // ListMessages mocks base method.
 func (m *MockStore) ListMessages(ctx context.Context, userID string) ([]store.Message, error) {
 	m.ctrl.T.Helper()
@@ -80,16 +139,120 @@ func (mr *MockStoreMockRecorder) ListMessages(ctx, userID interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListMessages", reflect.TypeOf((*MockStore)(nil).ListMessages), ctx, userID)
 }
 
-// SaveMessage mocks base method.
-func (m *MockStore) SaveMessage(ctx context.Context, userID string, msg store.Message) error {
+// ListReminders mocks base method.
+func (m *MockStore) ListReminders(ctx context.Context, userID string, before time.Time) ([]store.Reminder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReminders", ctx, userID, before)
+	ret0, _ := ret[0].([]store.Reminder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReminders indicates an expected call of ListReminders.
+func (mr *MockStoreMockRecorder) ListReminders(ctx, userID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReminders", reflect.TypeOf((*MockStore)(nil).ListReminders), ctx, userID, before)
+}
+
+// Ping mocks base method.
+func (m *MockStore) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockStoreMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStore)(nil).Ping), ctx)
+}
+
+// RegisterUser mocks base method.
+func (m *MockStore) RegisterUser(ctx context.Context, userID, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterUser", ctx, userID, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterUser indicates an expected call of RegisterUser.
+func (mr *MockStoreMockRecorder) RegisterUser(ctx, userID, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterUser", reflect.TypeOf((*MockStore)(nil).RegisterUser), ctx, userID, username)
+}
+
+// RescheduleReminder mocks base method.
+func (m *MockStore) RescheduleReminder(ctx context.Context, id int64, dueAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RescheduleReminder", ctx, id, dueAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RescheduleReminder indicates an expected call of RescheduleReminder.
+func (mr *MockStoreMockRecorder) RescheduleReminder(ctx, id, dueAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RescheduleReminder", reflect.TypeOf((*MockStore)(nil).RescheduleReminder), ctx, id, dueAt)
+}
+
+// SaveMessages mocks base method.
+func (m *MockStore) SaveMessages(ctx context.Context, messages ...store.Message) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range messages {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "SaveMessages", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveMessages indicates an expected call of SaveMessages.
+func (mr *MockStoreMockRecorder) SaveMessages(ctx interface{}, messages ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, messages...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMessages", reflect.TypeOf((*MockStore)(nil).SaveMessages), varargs...)
+}
+
+// SaveReminder mocks base method.
+func (m *MockStore) SaveReminder(ctx context.Context, reminder store.Reminder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveReminder", ctx, reminder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveReminder indicates an expected call of SaveReminder.
+func (mr *MockStoreMockRecorder) SaveReminder(ctx, reminder interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveReminder", reflect.TypeOf((*MockStore)(nil).SaveReminder), ctx, reminder)
+}
+
+// SaveSettings mocks base method.
+func (m *MockStore) SaveSettings(ctx context.Context, settings store.Settings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSettings", ctx, settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSettings indicates an expected call of SaveSettings.
+func (mr *MockStoreMockRecorder) SaveSettings(ctx, settings interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSettings", reflect.TypeOf((*MockStore)(nil).SaveSettings), ctx, settings)
+}
+
+// SaveUserState mocks base method.
+func (m *MockStore) SaveUserState(ctx context.Context, userID string, state []byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveMessage", ctx, userID, msg)
+	ret := m.ctrl.Call(m, "SaveUserState", ctx, userID, state)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveMessage indicates an expected call of SaveMessage.
-func (mr *MockStoreMockRecorder) SaveMessage(ctx, userID, msg interface{}) *gomock.Call {
+// SaveUserState indicates an expected call of SaveUserState.
+func (mr *MockStoreMockRecorder) SaveUserState(ctx, userID, state interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveMessage", reflect.TypeOf((*MockStore)(nil).SaveMessage), ctx, userID, msg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUserState", reflect.TypeOf((*MockStore)(nil).SaveUserState), ctx, userID, state)
 }
@@ -0,0 +1,89 @@
+package pg
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// mask заменяет пароль в строке соединения и в текстах ошибок
+const mask = "xxxxx"
+
+// пароль в строке соединения вида «host=localhost password=secret»; значение может быть в кавычках
+var passwordKV = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// пароль в URL, который не разобрал url.Parse, например из-за неверного экранирования
+var passwordURL = regexp.MustCompile(`(://[^:/@]*:)([^@]*)(@)`)
+
+// Open открывает пул соединений с PostgreSQL по строке соединения dsn.
+// Строка разбирается сразу, а не при первом запросе, и ошибки разбора не содержат пароля,
+// поэтому их можно писать в журнал и возвращать из main.
+func Open(dsn string) (*sql.DB, error) {
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse database URI %s: %w", RedactDSN(dsn), redactError(err, dsn))
+	}
+	return stdlib.OpenDB(*cfg), nil
+}
+
+// RedactDSN возвращает строку соединения в виде URL или «ключ=значение», в которой пароль заменён на xxxxx
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		// пароль можно передать и параметром запроса
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", mask)
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+	dsn = passwordURL.ReplaceAllString(dsn, "${1}"+mask+"${3}")
+	return passwordKV.ReplaceAllString(dsn, "${1}"+mask)
+}
+
+// password возвращает пароль из строки соединения или пустую строку
+func password(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		if p, ok := u.User.Password(); ok {
+			return p
+		}
+		return u.Query().Get("password")
+	}
+	if m := passwordURL.FindStringSubmatch(dsn); m != nil {
+		return m[2]
+	}
+	if m := passwordKV.FindStringSubmatch(dsn); m != nil {
+		return strings.Trim(m[2], "'")
+	}
+	return ""
+}
+
+// redactedError скрывает пароль в тексте ошибки драйвера, оставляя исходную ошибку доступной для errors.Is и errors.As
+type redactedError struct {
+	err      error
+	password string
+}
+
+// redactError оборачивает err так, чтобы её текст не содержал пароля из dsn
+func redactError(err error, dsn string) error {
+	p := password(dsn)
+	if p == "" {
+		return err
+	}
+	return &redactedError{err: err, password: p}
+}
+
+func (e *redactedError) Error() string {
+	msg := e.err.Error()
+	// драйвер может вывести пароль как есть или в URL-кодировке
+	msg = strings.ReplaceAll(msg, e.password, mask)
+	return strings.ReplaceAll(msg, url.QueryEscape(e.password), mask)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -1,67 +1,129 @@
 package pg
 
 import (
+	"alice-skill/internal/logger"
 	"alice-skill/internal/store"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
 )
 
 // Store реализует интерфейс store.Store и позволяет взаимодействовать с СУБД PostgreSQL
 type Store struct {
 	// Поле conn содержит объект соединения с СУБД
 	conn *sql.DB
+	// Поле log содержит логер для событий вне запросов навыка
+	log *zap.Logger
 }
 
-// NewStore возвращает нвоый экземпляр PostgreSQL-хранилища
-func NewStore(conn *sql.DB) *Store {
-	return &Store{conn: conn}
+// NewStore возвращает нвоый экземпляр PostgreSQL-хранилища.
+// События запросов пишутся в логер запроса из контекста, а если его нет — в log; nil отключает журнал.
+func NewStore(conn *sql.DB, log *zap.Logger) *Store {
+	if log == nil {
+		log = zap.NewNop()
+	}
+	return &Store{conn: conn, log: log}
 }
 
-// Bootstrap подготавливает БД к работе, создавая необходимые таблицы и индексы
-func (s Store) Bootstrap(ctx context.Context) error {
-	// запускаем транзакцию
-	tx, err := s.conn.BeginTx(ctx, nil)
-	if err != nil {
-		fmt.Printf("Ошибка запуска транзакции: %v", err)
-		return err
-	}
+// observe пишет в журнал событие запроса op с его длительностью и дополняет ошибку *errp именем операции.
+// Вызывается через defer с именованным результатом err, поэтому errors.Is для ErrNotFound и ErrConflict продолжает работать.
+func (s Store) observe(ctx context.Context, op string, start time.Time, errp *error) {
+	log := logger.FromContextOr(ctx, s.log).With(
+		zap.String("query", op),
+		zap.Duration("duration", time.Since(start)),
+	)
 
-	// в случае неуспешного коммита все изменения транзации будут отменены
-	defer tx.Rollback()
+	err := *errp
+	switch {
+	case err == nil:
+		log.Debug("query done")
+		return
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrConflict):
+		// ожидаемые ответы хранилища обрабатывает навык, это не сбой
+		log.Debug("query done", zap.Error(err))
+	default:
+		log.Error("query failed", zap.Error(err))
+	}
+	*errp = fmt.Errorf("pg.%s: %w", op, err)
+}
 
-	// создаём таблицу пользователей и необходимые индексы
-	tx.ExecContext(ctx, `
-	CREATE TABLE users (
+// schema описывает таблицы и индексы навыка. Все выражения идемпотентны: Bootstrap выполняется при каждом запуске,
+// в том числе на базе, созданной предыдущей версией навыка, и досоздаёт только недостающее.
+var schema = []string{
+	// таблица пользователей и необходимые индексы
+	`CREATE TABLE IF NOT EXISTS users (
 		id VARCHAR(128) PRIMARY KEY,
 		username VARCHAR(128)
-	);
-	`)
+	);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS sender_idx ON users (username);`,
 
-	tx.ExecContext(ctx, `
-	CREATE UNIQUE INDEX sender_idx ON users (username);
-	`)
-
-	// создаём таблицу сообщений и необходимые индексы
-	tx.ExecContext(ctx, `
-	CREATE TABLE messages (
+	// таблица сообщений и необходимые индексы
+	`CREATE TABLE IF NOT EXISTS messages (
 		id SERIAL PRIMARY KEY,
 		sender VARCHAR(128),
 		recipient VARCHAR(128),
 		payload TEXT,
 		sent_at TIMESTAMP WITH TIME ZONE,
 		read_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
-	);
-	`)
+	);`,
+	`CREATE INDEX IF NOT EXISTS recipient_idx ON messages (recipient);`,
+
+	// таблица напоминаний и необходимые индексы
+	`CREATE TABLE IF NOT EXISTS reminders (
+		id SERIAL PRIMARY KEY,
+		user_id VARCHAR(128),
+		payload TEXT,
+		recurrence VARCHAR(16),
+		due_at TIMESTAMP WITH TIME ZONE,
+		done_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS reminder_user_idx ON reminders (user_id, due_at);`,
+	// расписание отделено от времени срабатывания, чтобы откладывание не сдвигало повторения;
+	// в базах, созданных до появления столбца, расписанием служит время срабатывания
+	`ALTER TABLE reminders ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP WITH TIME ZONE;`,
+
+	// таблица пользовательских настроек
+	`CREATE TABLE IF NOT EXISTS user_settings (
+		user_id VARCHAR(128) PRIMARY KEY,
+		timezone VARCHAR(64),
+		announce_time BOOLEAN,
+		verbosity VARCHAR(16),
+		read_order VARCHAR(16)
+	);`,
 
-	tx.ExecContext(ctx, `
-	CREATE INDEX recipient_idx ON messages (recipient);
-	`)
+	// таблица состояний диалога для клиентов без хранилища состояний Алисы
+	`CREATE TABLE IF NOT EXISTS user_states (
+		user_id VARCHAR(128) PRIMARY KEY,
+		state JSONB
+	);`,
+}
+
+// Bootstrap подготавливает БД к работе, создавая недостающие таблицы и индексы
+func (s Store) Bootstrap(ctx context.Context) (err error) {
+	defer s.observe(ctx, "Bootstrap", time.Now(), &err)
+
+	// запускаем транзакцию
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// в случае неуспешного коммита все изменения транзации будут отменены
+	defer tx.Rollback()
+
+	// после первой ошибки транзакция прервана, поэтому остальные выражения не выполняем
+	for _, stmt := range schema {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("cannot apply schema: %w", err)
+		}
+	}
 
 	// коммитим транзакцию
 	return tx.Commit()
@@ -69,6 +131,8 @@ func (s Store) Bootstrap(ctx context.Context) error {
 
 // FindRecipient ищет в БД userID по username
 func (s Store) FindRecipient(ctx context.Context, username string) (userID string, err error) {
+	defer s.observe(ctx, "FindRecipient", time.Now(), &err)
+
 	// запрашиваем внутренний идентификатор пользователя по его имени
 	row := s.conn.QueryRowContext(ctx, `
 	SELECT id FROM users
@@ -76,15 +140,16 @@ func (s Store) FindRecipient(ctx context.Context, username string) (userID strin
 	`, username)
 
 	err = row.Scan(&userID)
-	if err != nil {
-		fmt.Printf("Ошибка запроса внутреннего идентификатора пользователя по его имени: %v", err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", store.ErrNotFound
 	}
-
 	return
 }
 
 // ListMessages ищет в БД все сообщения пользователя с userID
-func (s Store) ListMessages(ctx context.Context, userID string) ([]store.Message, error) {
+func (s Store) ListMessages(ctx context.Context, userID string) (_ []store.Message, err error) {
+	defer s.observe(ctx, "ListMessages", time.Now(), &err)
+
 	// запрашиваем данные обо всех сообщениях пользователя, без самого текста
 	rows, err := s.conn.QueryContext(ctx, `
 	SELECT
@@ -97,7 +162,6 @@ func (s Store) ListMessages(ctx context.Context, userID string) ([]store.Message
 	`, userID)
 
 	if err != nil {
-		fmt.Printf("Ошибка получения сообщений из БД: %v", err)
 		return nil, err
 	}
 
@@ -110,7 +174,6 @@ func (s Store) ListMessages(ctx context.Context, userID string) ([]store.Message
 	for rows.Next() {
 		var m store.Message
 		if err := rows.Scan(&m.ID, &m.Sender, &m.Time); err != nil {
-			fmt.Printf("Ошибка считывания записи из БД в слайс: %v", err)
 			return nil, err
 		}
 		messages = append(messages, m)
@@ -118,7 +181,6 @@ func (s Store) ListMessages(ctx context.Context, userID string) ([]store.Message
 
 	// проверка ошибки уровня курсора
 	if err := rows.Err(); err != nil {
-		fmt.Printf("Ошибка уровня курсора: %v", err)
 		return nil, err
 	}
 
@@ -126,7 +188,9 @@ func (s Store) ListMessages(ctx context.Context, userID string) ([]store.Message
 }
 
 // GetMessage получает сообщение по внутреннему идентификатору
-func (s Store) GetMessage(ctx context.Context, id int64) (*store.Message, error) {
+func (s Store) GetMessage(ctx context.Context, id int64) (_ *store.Message, err error) {
+	defer s.observe(ctx, "GetMessage", time.Now(), &err)
+
 	// запрашиваем сообщение по внутреннему идентификатору
 	row := s.conn.QueryRowContext(ctx, `
 	SELECT
@@ -141,16 +205,20 @@ func (s Store) GetMessage(ctx context.Context, id int64) (*store.Message, error)
 
 	// считываем значения из записи БД в соответствующие поля структуры
 	var msg store.Message
-	err := row.Scan(&msg.ID, &msg.Sender, &msg.Payload, &msg.Time)
+	err = row.Scan(&msg.ID, &msg.Sender, &msg.Payload, &msg.Time)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
 	if err != nil {
-		fmt.Printf("Ошибка получения данных из БД: %v", err)
 		return nil, err
 	}
 	return &msg, nil
 }
 
 // SaveMessage добавляет новое сообщение в БД
-func (s Store) SaveMessages(ctx context.Context, messages ...store.Message) error {
+func (s Store) SaveMessages(ctx context.Context, messages ...store.Message) (err error) {
+	defer s.observe(ctx, "SaveMessages", time.Now(), &err)
+
 	// соберём данные для создания запроса с групповой вставкой
 	var values []string
 	var args []any
@@ -166,24 +234,21 @@ func (s Store) SaveMessages(ctx context.Context, messages ...store.Message) erro
 	// составляем строку запроса
 	query := `
 		INSERT INTO messages
-			(sender, recepient, payload, sent_at)
+			(sender, recipient, payload, sent_at)
 		VALUES 
 	` + strings.Join(values, ",") + `;`
 
 	// добавляем новые сообщения в БД
-	_, err := s.conn.ExecContext(ctx, query, args...)
-
-	if err != nil {
-		fmt.Printf("Ошибка добавления сообщения в БД: %v", err)
-	}
-
+	_, err = s.conn.ExecContext(ctx, query, args...)
 	return err
 }
 
 // RegisterUser добавляет новую запись пользователя
-func (s Store) RegisterUser(ctx context.Context, userID, username string) error {
+func (s Store) RegisterUser(ctx context.Context, userID, username string) (err error) {
+	defer s.observe(ctx, "RegisterUser", time.Now(), &err)
+
 	// добавляем новую запись пользователя
-	_, err := s.conn.ExecContext(ctx, `
+	_, err = s.conn.ExecContext(ctx, `
 		INSERT INTO users
 			(id, username)
 		VALUES
@@ -198,3 +263,169 @@ func (s Store) RegisterUser(ctx context.Context, userID, username string) error
 	}
 	return err
 }
+
+// SaveReminder добавляет новое напоминание в БД
+func (s Store) SaveReminder(ctx context.Context, reminder store.Reminder) (err error) {
+	defer s.observe(ctx, "SaveReminder", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		INSERT INTO reminders
+			(user_id, payload, recurrence, due_at, scheduled_at)
+		VALUES
+			($1, $2, $3, $4, $5);
+		`, reminder.UserID, reminder.Payload, string(reminder.Recurrence), reminder.DueAt, reminder.ScheduledAt)
+	return err
+}
+
+// ListReminders ищет в БД невыполненные напоминания пользователя, время которых наступило до before
+func (s Store) ListReminders(ctx context.Context, userID string, before time.Time) (_ []store.Reminder, err error) {
+	defer s.observe(ctx, "ListReminders", time.Now(), &err)
+
+	rows, err := s.conn.QueryContext(ctx, `
+	SELECT id, user_id, payload, recurrence, due_at, COALESCE(scheduled_at, due_at)
+	FROM reminders
+	WHERE user_id = $1 AND done_at IS NULL AND due_at <= $2
+	ORDER BY due_at;
+	`, userID, before)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// не забываем закрыть курсор после завершения работы с данными
+	defer rows.Close()
+
+	var reminders []store.Reminder
+
+	for rows.Next() {
+		var r store.Reminder
+		var rec string
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Payload, &rec, &r.DueAt, &r.ScheduledAt); err != nil {
+			return nil, err
+		}
+		r.Recurrence = store.Recurrence(rec)
+		reminders = append(reminders, r)
+	}
+
+	// проверка ошибки уровня курсора
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
+
+// RescheduleReminder переносит напоминание на новое время, не меняя его расписания
+func (s Store) RescheduleReminder(ctx context.Context, id int64, dueAt time.Time) (err error) {
+	defer s.observe(ctx, "RescheduleReminder", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		UPDATE reminders SET due_at = $2 WHERE id = $1;
+		`, id, dueAt)
+	return err
+}
+
+// AdvanceReminder переносит повторяющееся напоминание на следующее срабатывание по расписанию
+func (s Store) AdvanceReminder(ctx context.Context, id int64, scheduledAt time.Time) (err error) {
+	defer s.observe(ctx, "AdvanceReminder", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		UPDATE reminders SET due_at = $2, scheduled_at = $2 WHERE id = $1;
+		`, id, scheduledAt)
+	return err
+}
+
+// CompleteReminder отмечает напоминание выполненным
+func (s Store) CompleteReminder(ctx context.Context, id int64) (err error) {
+	defer s.observe(ctx, "CompleteReminder", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		UPDATE reminders SET done_at = NOW() WHERE id = $1;
+		`, id)
+	return err
+}
+
+// GetSettings получает настройки пользователя
+func (s Store) GetSettings(ctx context.Context, userID string) (_ *store.Settings, err error) {
+	defer s.observe(ctx, "GetSettings", time.Now(), &err)
+
+	row := s.conn.QueryRowContext(ctx, `
+	SELECT user_id, timezone, announce_time, verbosity, read_order
+	FROM user_settings
+	WHERE user_id = $1;
+	`, userID)
+
+	var settings store.Settings
+	var verbosity, readOrder string
+	err = row.Scan(&settings.UserID, &settings.Timezone, &settings.AnnounceTime, &verbosity, &readOrder)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings.Verbosity = store.Verbosity(verbosity)
+	settings.ReadOrder = store.ReadOrder(readOrder)
+
+	return &settings, nil
+}
+
+// SaveSettings добавляет или обновляет настройки пользователя
+func (s Store) SaveSettings(ctx context.Context, settings store.Settings) (err error) {
+	defer s.observe(ctx, "SaveSettings", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		INSERT INTO user_settings
+			(user_id, timezone, announce_time, verbosity, read_order)
+		VALUES
+			($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			announce_time = EXCLUDED.announce_time,
+			verbosity = EXCLUDED.verbosity,
+			read_order = EXCLUDED.read_order;
+		`, settings.UserID, settings.Timezone, settings.AnnounceTime, string(settings.Verbosity), string(settings.ReadOrder))
+	return err
+}
+
+// GetUserState получает состояние диалога пользователя
+func (s Store) GetUserState(ctx context.Context, userID string) (_ []byte, err error) {
+	defer s.observe(ctx, "GetUserState", time.Now(), &err)
+
+	row := s.conn.QueryRowContext(ctx, `
+	SELECT state FROM user_states
+	WHERE user_id = $1;
+	`, userID)
+
+	var state []byte
+	err = row.Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SaveUserState добавляет или обновляет состояние диалога пользователя
+func (s Store) SaveUserState(ctx context.Context, userID string, state []byte) (err error) {
+	defer s.observe(ctx, "SaveUserState", time.Now(), &err)
+
+	_, err = s.conn.ExecContext(ctx, `
+		INSERT INTO user_states
+			(user_id, state)
+		VALUES
+			($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			state = EXCLUDED.state;
+		`, userID, state)
+	return err
+}
+
+// Ping проверяет соединение с СУБД
+func (s Store) Ping(ctx context.Context) (err error) {
+	defer s.observe(ctx, "Ping", time.Now(), &err)
+
+	return s.conn.PingContext(ctx)
+}
@@ -0,0 +1,150 @@
+package pg
+
+import (
+	"alice-skill/internal/logger"
+	"alice-skill/internal/store"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestObserve(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	s := NewStore(nil, zap.New(core))
+
+	testCases := []struct {
+		name      string
+		err       error
+		wantErr   string
+		wantLevel zapcore.Level
+	}{
+		{
+			name:      "success",
+			wantLevel: zapcore.DebugLevel,
+		},
+		{
+			name:      "not_found",
+			err:       store.ErrNotFound,
+			wantErr:   "pg.ListMessages: not found",
+			wantLevel: zapcore.DebugLevel,
+		},
+		{
+			name:      "failure",
+			err:       errors.New("connection reset"),
+			wantErr:   "pg.ListMessages: connection reset",
+			wantLevel: zapcore.ErrorLevel,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			err := tc.err
+			s.observe(context.Background(), "ListMessages", time.Now(), &err)
+
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+				// сигнальные ошибки распознаются и после обёртки
+				assert.ErrorIs(t, err, tc.err)
+			}
+
+			entries := logs.TakeAll()
+			if assert.Len(t, entries, 1) {
+				assert.Equal(t, tc.wantLevel, entries[0].Level)
+				assert.Equal(t, "ListMessages", entries[0].ContextMap()["query"])
+				assert.Contains(t, entries[0].ContextMap(), "duration")
+			}
+		})
+	}
+}
+
+func TestObserveRequestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	s := NewStore(nil, zap.NewNop())
+
+	// событие запроса попадает в логер запроса вместе с его идентификатором
+	ctx := logger.WithLogger(context.Background(), zap.New(core).With(zap.String("request_id", "req-1")))
+	var err error
+	s.observe(ctx, "GetSettings", time.Now(), &err)
+
+	if assert.Equal(t, 1, logs.Len()) {
+		assert.Equal(t, "req-1", logs.All()[0].ContextMap()["request_id"])
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	db := &fakeDB{}
+	require.NoError(t, NewStore(openFake(t, db), nil).Bootstrap(context.Background()))
+
+	// повторный запуск на существующей базе не должен падать на уже созданных таблицах
+	assert.Equal(t, schema, db.execs)
+	for _, stmt := range db.execs {
+		assert.Contains(t, stmt, "IF NOT EXISTS")
+	}
+	assert.True(t, db.committed)
+}
+
+func TestBootstrapError(t *testing.T) {
+	db := &fakeDB{failOn: "reminders"}
+	err := NewStore(openFake(t, db), nil).Bootstrap(context.Background())
+
+	assert.ErrorContains(t, err, "pg.Bootstrap: cannot apply schema: relation already exists")
+	// после ошибки транзакция откатывается, оставшиеся выражения не выполняются
+	assert.False(t, db.committed)
+	assert.Less(t, len(db.execs), len(schema))
+}
+
+func TestNotFound(t *testing.T) {
+	// на любой запрос fakeDB возвращает пустую выборку
+	s := NewStore(openFake(t, &fakeDB{}), nil)
+	ctx := context.Background()
+
+	_, err := s.FindRecipient(ctx, "ivan")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.GetMessage(ctx, 1)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.GetSettings(ctx, "user1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	_, err = s.GetUserState(ctx, "user1")
+	assert.ErrorIs(t, err, store.ErrNotFound)
+}
+
+func TestSaveMessagesColumns(t *testing.T) {
+	db := &fakeDB{}
+	s := NewStore(openFake(t, db), nil)
+	require.NoError(t, s.SaveMessages(context.Background(),
+		store.Message{Sender: "user1", Recepient: "user2", Payload: "раз", Time: time.Now()},
+		store.Message{Sender: "user2", Recepient: "user1", Payload: "два", Time: time.Now()},
+	))
+	require.Len(t, db.execs, 1)
+
+	// столбцы вставки должны совпадать со столбцами таблицы, которую создаёт Bootstrap
+	var table string
+	for _, stmt := range schema {
+		if strings.Contains(stmt, "CREATE TABLE IF NOT EXISTS messages") {
+			table = stmt
+		}
+	}
+	require.NotEmpty(t, table)
+
+	m := regexp.MustCompile(`INSERT INTO messages\s*\(([^)]*)\)`).FindStringSubmatch(db.execs[0])
+	require.NotNil(t, m, db.execs[0])
+	for _, column := range strings.Split(m[1], ",") {
+		assert.Regexp(t, `\n\s*`+strings.TrimSpace(column)+` `, table, "column %q is not in the messages table", column)
+	}
+}
@@ -9,6 +9,9 @@ import (
 // ErrConflict указывает на конфликт данных в хранилище
 var ErrConflict = errors.New("data conflict")
 
+// ErrNotFound указывает на отсутствие запрошенных данных в хранилище
+var ErrNotFound = errors.New("not found")
+
 // Store описывает абстрактное хранилище сообщений пользователей
 type Store interface {
 	// FindRecipient возвращает внутренний идентификатор пользователя по человекопонятному имени
@@ -21,6 +24,26 @@ type Store interface {
 	SaveMessages(ctx context.Context, messages ...Message) error
 	// RegisterUser регистрирует нового пользователя
 	RegisterUser(ctx context.Context, userID, username string) error
+	// SaveReminder сохраняет новое напоминание
+	SaveReminder(ctx context.Context, reminder Reminder) error
+	// ListReminders возвращает активные напоминания пользователя, время которых наступило до момента before
+	ListReminders(ctx context.Context, userID string, before time.Time) ([]Reminder, error)
+	// RescheduleReminder переносит напоминание на новое время, не меняя его расписания
+	RescheduleReminder(ctx context.Context, id int64, dueAt time.Time) error
+	// AdvanceReminder переносит повторяющееся напоминание на следующее срабатывание по расписанию
+	AdvanceReminder(ctx context.Context, id int64, scheduledAt time.Time) error
+	// CompleteReminder отмечает напоминание выполненным
+	CompleteReminder(ctx context.Context, id int64) error
+	// GetSettings возвращает настройки пользователя или ErrNotFound, если пользователь их не менял
+	GetSettings(ctx context.Context, userID string) (*Settings, error)
+	// SaveSettings сохраняет настройки пользователя, перезаписывая предыдущие
+	SaveSettings(ctx context.Context, settings Settings) error
+	// GetUserState возвращает сериализованное в JSON состояние диалога пользователя или ErrNotFound
+	GetUserState(ctx context.Context, userID string) ([]byte, error)
+	// SaveUserState сохраняет сериализованное в JSON состояние диалога пользователя
+	SaveUserState(ctx context.Context, userID string, state []byte) error
+	// Ping проверяет, что хранилище доступно
+	Ping(ctx context.Context) error
 }
 
 // Message описывает объект сообщения
@@ -31,3 +54,58 @@ type Message struct {
 	Time      time.Time // время отправления
 	Payload   string    // текст сообщения
 }
+
+// Recurrence описывает правило повторения напоминания
+type Recurrence string
+
+const (
+	RecurrenceNone     Recurrence = ""         // однократное напоминание
+	RecurrenceDaily    Recurrence = "daily"    // каждый день
+	RecurrenceWeekdays Recurrence = "weekdays" // по будним дням
+	RecurrenceWeekly   Recurrence = "weekly"   // раз в неделю
+)
+
+// Reminder описывает объект напоминания пользователя самому себе
+type Reminder struct {
+	ID          int64      // внутренний идентификатор напоминания
+	UserID      string     // владелец напоминания
+	DueAt       time.Time  // время ближайшего срабатывания
+	ScheduledAt time.Time  // время срабатывания по расписанию, откладывание его не меняет
+	Recurrence  Recurrence // правило повторения
+	Payload     string     // текст напоминания
+}
+
+// Verbosity описывает подробность ответов навыка
+type Verbosity string
+
+const (
+	VerbosityLong  Verbosity = "long"  // развёрнутые ответы
+	VerbosityShort Verbosity = "short" // краткие ответы
+)
+
+// ReadOrder описывает порядок, в котором зачитываются сообщения
+type ReadOrder string
+
+const (
+	ReadOrderOldest ReadOrder = "oldest" // сначала старые
+	ReadOrderNewest ReadOrder = "newest" // сначала новые
+)
+
+// Settings описывает персональные настройки пользователя
+type Settings struct {
+	UserID       string    // владелец настроек
+	Timezone     string    // предпочитаемый часовой пояс, пустая строка — брать из запроса
+	AnnounceTime bool      // называть ли время в приветствии
+	Verbosity    Verbosity // подробность ответов
+	ReadOrder    ReadOrder // порядок чтения сообщений
+}
+
+// DefaultSettings возвращает настройки, действующие для пользователя, который их не менял
+func DefaultSettings(userID string) Settings {
+	return Settings{
+		UserID:       userID,
+		AnnounceTime: true,
+		Verbosity:    VerbosityLong,
+		ReadOrder:    ReadOrderOldest,
+	}
+}
@@ -0,0 +1,67 @@
+// Package tracing настраивает трассировку OpenTelemetry и экспорт спанов.
+// Спаны создаются через глобальный провайдер otel, поэтому пакеты навыка не зависят от выбранного экспортёра.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName — имя навыка в трассах
+const ServiceName = "alice-skill"
+
+// экспортёры спанов
+const (
+	ExporterNone   = "none"   // трассировка выключена
+	ExporterStdout = "stdout" // спаны печатаются в стандартный вывод, удобно для локальной отладки
+	ExporterOTLP   = "otlp"   // спаны отправляются коллектору по OTLP/HTTP, адрес задаётся переменными OTEL_EXPORTER_OTLP_*
+)
+
+// Setup устанавливает глобальный провайдер трассировки с экспортёром exporter.
+// Возвращённая функция отправляет накопленные спаны и останавливает провайдер, её нужно вызвать при завершении работы.
+func Setup(ctx context.Context, exporter string) (shutdown func(context.Context) error, err error) {
+	// заголовки traceparent входящих запросов продолжают трассу вызывающей стороны
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exp sdktrace.SpanExporter
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("cannot describe trace resource: %w", err), exp.Shutdown(ctx))
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}